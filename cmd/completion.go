@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// loadCompletionConfig loads the host configuration for shell completion.
+// PersistentPreRunE does not run while completing, so the global flags are
+// applied here. Errors yield no configuration rather than failing completion.
+func loadCompletionConfig() *config.HostConfig {
+	if err := applyGlobalFlags(nil, nil); err != nil {
+		return nil
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// groupCandidates returns @group selectors for every group and cached class
+func groupCandidates(cfg *config.HostConfig) []string {
+	var candidates []string
+	for _, name := range cfg.GroupNames() {
+		candidates = append(candidates, "@"+name)
+	}
+
+	classes := make(map[string]bool)
+	for _, class := range cfg.Classes {
+		classes[class] = true
+	}
+	classNames := make([]string, 0, len(classes))
+	for class := range classes {
+		classNames = append(classNames, class)
+	}
+	sort.Strings(classNames)
+	for _, class := range classNames {
+		candidates = append(candidates, "@class:"+class)
+	}
+	return candidates
+}
+
+// filterCandidates returns the candidates that start with toComplete and are
+// not already present in args
+func filterCandidates(candidates, args []string, toComplete string) []string {
+	used := make(map[string]bool, len(args))
+	for _, arg := range args {
+		used[arg] = true
+	}
+
+	var filtered []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, toComplete) && !used[candidate] {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// completeHosts completes hostnames and @group selectors
+func completeHosts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadCompletionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	candidates := append(cfg.AllHosts(), groupCandidates(cfg)...)
+	return filterCandidates(candidates, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGroups completes group names without the @ prefix
+func completeGroups(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadCompletionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCandidates(cfg.GroupNames(), args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFirstGroupThenHosts completes a group name as the first argument
+// and hostnames afterwards
+func completeFirstGroupThenHosts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeGroups(cmd, args, toComplete)
+	}
+
+	cfg := loadCompletionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCandidates(cfg.AllHosts(), args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeExec completes hosts and groups before the "--" separator of exec
+// and falls back to the shell's default completion for the command after it.
+// Flag parsing is disabled for exec, so args still contain its flags.
+func completeExec(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	for _, arg := range args {
+		if arg == "--" {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+	}
+
+	args, err := extractGlobalFlags(args)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	if strings.HasPrefix(toComplete, "-") {
+		flags := []string{"--", "--interactive", "--template", "--yes", "--config", "--inventory", "--include-maint"}
+		return filterCandidates(flags, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeHosts(cmd, args, toComplete)
+}
+
+// completeInventories completes the names of named inventories
+func completeInventories(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := config.ListInventories()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCandidates(names, nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGroupToRename completes the group name given as the first argument
+func completeGroupToRename(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeGroups(cmd, args, toComplete)
+}
+
+// completeNewGroupMembers completes members after the new group's name
+func completeNewGroupMembers(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeHosts(cmd, args, toComplete)
+}
+
+// completeStatusColumns completes the comma-separated column names of
+// status --columns
+func completeStatusColumns(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := append([]string{}, builtinColumns...)
+	if cfg := loadCompletionConfig(); cfg != nil {
+		names = append(names, cfg.ColumnNames()...)
+	}
+	names = append(names, optionalColumns...)
+
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	} else if strings.HasPrefix(toComplete, "+") {
+		prefix = "+"
+	}
+	used := strings.Split(prefix, ",")
+
+	var candidates []string
+	for _, name := range filterCandidates(names, used, toComplete[len(prefix):]) {
+		candidates = append(candidates, prefix+name)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeStatusSort completes the columns status can sort by, ascending and
+// descending
+func completeStatusSort(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := append([]string{"hostname"}, builtinColumns...)
+	if cfg := loadCompletionConfig(); cfg != nil {
+		names = append(names, cfg.ColumnNames()...)
+	}
+	names = append(names, optionalColumns...)
+
+	prefix := ""
+	if strings.HasPrefix(toComplete, "-") {
+		prefix = "-"
+	}
+	var candidates []string
+	for _, name := range filterCandidates(names, nil, toComplete[len(prefix):]) {
+		candidates = append(candidates, prefix+name)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
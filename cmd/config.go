@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/config"
+	"github.com/claby2/hladmin/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var checkProbe bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect, validate and edit the host configuration",
+	Long:  "Inspect, validate and edit the host configuration file. Edits keep comments and directive order, replace the file atomically and leave the previous version in a .bak file.",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:           "show",
+	Short:         "Show the host configuration",
+	Long:          "Show the groups, default group and other settings loaded from the host configuration.",
+	Args:          cobra.NoArgs,
+	RunE:          runConfigShow,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var configAddHostCmd = &cobra.Command{
+	Use:               "add-host <group> <hostname> [hostname...]",
+	Short:             "Add hosts to a group",
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runConfigAddHost,
+	ValidArgsFunction: completeFirstGroupThenHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var configRemoveHostCmd = &cobra.Command{
+	Use:               "remove-host <hostname> [hostname...]",
+	Short:             "Remove hosts from every group",
+	Long:              "Remove hosts from every group that lists them. Groups left without members are deleted, unless other directives refer to them.",
+	Args:              cobra.MinimumNArgs(1),
+	RunE:              runConfigRemoveHost,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var configAddGroupCmd = &cobra.Command{
+	Use:               "add-group <group> <hostname|@group> [hostname|@group...]",
+	Short:             "Define a new group",
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runConfigAddGroup,
+	ValidArgsFunction: completeNewGroupMembers,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var configSetDefaultCmd = &cobra.Command{
+	Use:               "set-default <group>",
+	Short:             "Set the default group",
+	Args:              cobra.ExactArgs(1),
+	RunE:              runConfigSetDefault,
+	ValidArgsFunction: completeGroups,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var configRenameGroupCmd = &cobra.Command{
+	Use:               "rename-group <old> <new>",
+	Short:             "Rename a group",
+	Long:              "Rename a group, updating @group references in other groups, directives targeting the group and the default group.",
+	Args:              cobra.ExactArgs(2),
+	RunE:              runConfigRenameGroup,
+	ValidArgsFunction: completeGroupToRename,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:           "check",
+	Short:         "Validate the host configuration",
+	Long:          "Report every problem in the host configuration with its file and line, and exit non-zero if any are found. With --probe, also check that every host resolves and accepts SSH connections.",
+	Args:          cobra.NoArgs,
+	RunE:          runConfigCheck,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	configCheckCmd.Flags().BoolVar(&checkProbe, "probe", false, "Check that every host resolves and is reachable over SSH")
+	configCmd.AddCommand(configCheckCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configAddHostCmd)
+	configCmd.AddCommand(configRemoveHostCmd)
+	configCmd.AddCommand(configAddGroupCmd)
+	configCmd.AddCommand(configSetDefaultCmd)
+	configCmd.AddCommand(configRenameGroupCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s\n\n", colors.Info.Sprint("Config:"), config.GetConfigPath())
+	showFullConfiguration(cfg)
+	return nil
+}
+
+// editConfig opens the config file, applies edit and saves the result. The
+// messages returned by edit are printed once the file has been saved.
+func editConfig(edit func(*config.Editor) ([]string, error)) error {
+	editor, err := config.OpenEditor()
+	if err != nil {
+		return err
+	}
+	messages, err := edit(editor)
+	if err != nil {
+		return err
+	}
+	if err := editor.Save(); err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		colors.Success.Println(message)
+	}
+	return nil
+}
+
+func runConfigAddHost(cmd *cobra.Command, args []string) error {
+	group, hosts := strings.TrimPrefix(args[0], "@"), args[1:]
+	return editConfig(func(editor *config.Editor) ([]string, error) {
+		added, err := editor.AddHosts(group, hosts...)
+		if err != nil {
+			return nil, err
+		}
+		if len(added) == 0 {
+			return []string{fmt.Sprintf("All hosts are already in @%s", group)}, nil
+		}
+		return []string{fmt.Sprintf("Added %s to @%s", strings.Join(added, ", "), group)}, nil
+	})
+}
+
+func runConfigRemoveHost(cmd *cobra.Command, args []string) error {
+	return editConfig(func(editor *config.Editor) ([]string, error) {
+		var messages []string
+		for _, host := range args {
+			groups, err := editor.RemoveHost(host)
+			if err != nil {
+				return nil, err
+			}
+			if len(groups) == 0 {
+				return nil, fmt.Errorf("%s is not in any group defined in %s", host, editor.Path())
+			}
+			messages = append(messages, fmt.Sprintf("Removed %s from @%s", host, strings.Join(groups, ", @")))
+		}
+		return messages, nil
+	})
+}
+
+func runConfigAddGroup(cmd *cobra.Command, args []string) error {
+	group, members := args[0], args[1:]
+	if strings.HasPrefix(group, "@") {
+		return fmt.Errorf("group names must not start with '@': %s", group)
+	}
+	return editConfig(func(editor *config.Editor) ([]string, error) {
+		if err := editor.AddGroup(group, members...); err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("Added group @%s", group)}, nil
+	})
+}
+
+func runConfigSetDefault(cmd *cobra.Command, args []string) error {
+	group := strings.TrimPrefix(args[0], "@")
+
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return err
+	}
+	if _, exists := cfg.Groups[group]; !exists {
+		return fmt.Errorf("unknown group: %s", group)
+	}
+
+	return editConfig(func(editor *config.Editor) ([]string, error) {
+		editor.SetDefault(group)
+		return []string{fmt.Sprintf("Default group set to @%s", group)}, nil
+	})
+}
+
+func runConfigRenameGroup(cmd *cobra.Command, args []string) error {
+	oldName := strings.TrimPrefix(args[0], "@")
+	newName := strings.TrimPrefix(args[1], "@")
+	return editConfig(func(editor *config.Editor) ([]string, error) {
+		if err := editor.RenameGroup(oldName, newName); err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("Renamed @%s to @%s", oldName, newName)}, nil
+	})
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) error {
+	cfg, problems, err := config.Check()
+	if err != nil {
+		return err
+	}
+
+	if checkProbe {
+		probeProblems, err := probeHosts(cfg)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, probeProblems...)
+		config.SortProblems(problems)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(problems), config.GetConfigPath())
+	}
+
+	colors.Success.Printf("%s: no problems found\n", config.GetConfigPath())
+	return nil
+}
+
+// probeHosts reports hosts whose SSH destination does not resolve and hosts
+// that do not accept an SSH connection
+func probeHosts(cfg *config.HostConfig) ([]config.Problem, error) {
+	useConnectionSettings(cfg)
+
+	hosts := cfg.AllHosts()
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	hostProblem := func(host, format string, args ...interface{}) config.Problem {
+		file, line := cfg.HostLocation(host)
+		return config.Problem{File: file, Line: line, Message: fmt.Sprintf(format, args...)}
+	}
+
+	var problems []config.Problem
+	var reachable []string
+	for _, host := range hosts {
+		if host == "localhost" {
+			continue
+		}
+		address, err := sshHostname(host)
+		if err != nil {
+			problems = append(problems, hostProblem(host, "host '%s' could not be resolved: %v", host, err))
+			continue
+		}
+		if net.ParseIP(address) == nil {
+			if _, err := net.LookupHost(address); err != nil {
+				problems = append(problems, hostProblem(host, "host '%s' could not be resolved: %v", host, err))
+				continue
+			}
+		}
+		reachable = append(reachable, host)
+	}
+
+	if len(reachable) == 0 {
+		return problems, nil
+	}
+
+	results, err := executor.ExecuteOnHostsParallelWithProgress(reachable, "true", "Probing hosts")
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			problems = append(problems, hostProblem(result.Hostname, "host '%s' is unreachable: %s", result.Hostname, strings.TrimSpace(result.Stderr)))
+		}
+	}
+	return problems, nil
+}
+
+// sshHostname returns the address ssh connects to for host, taking the host's
+// connection settings and ~/.ssh/config into account
+func sshHostname(host string) (string, error) {
+	output, err := exec.Command("ssh", executor.SSHArgs(host, []string{"-G"})...).Output()
+	if err != nil {
+		return "", fmt.Errorf("ssh -G failed: %v", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "hostname" {
+			return fields[1], nil
+		}
+	}
+	return host, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/config"
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal reports whether standard input is an interactive terminal
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirmTargets asks for confirmation before a mutating command runs on
+// hostnames. It asks when the hosts outnumber the configured threshold or
+// include protected hosts, whose names must be typed out. Without a terminal
+// it refuses instead of asking. assumeYes skips the confirmation.
+func confirmTargets(cfg *config.HostConfig, hostnames []string, action string, assumeYes bool) error {
+	if assumeYes {
+		return nil
+	}
+
+	var protected []string
+	for _, hostname := range hostnames {
+		if cfg.HostSettings(hostname).Protected {
+			protected = append(protected, hostname)
+		}
+	}
+	threshold := cfg.ConfirmThreshold()
+	if len(protected) == 0 && (threshold == 0 || len(hostnames) <= threshold) {
+		return nil
+	}
+
+	if !stdinIsTerminal() {
+		if len(protected) > 0 {
+			return fmt.Errorf("refusing to %s on protected hosts (%s) without confirmation; use --yes", action, strings.Join(protected, ", "))
+		}
+		return fmt.Errorf("refusing to %s on %d hosts without confirmation; use --yes", action, len(hostnames))
+	}
+
+	colors.Warning.Fprintf(os.Stderr, "About to %s on %d hosts: %s\n", action, len(hostnames), strings.Join(hostnames, ", "))
+	reader := bufio.NewReader(os.Stdin)
+
+	if len(protected) == 0 {
+		fmt.Fprint(os.Stderr, "Continue? [y/N] ")
+		answer, _ := reader.ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return fmt.Errorf("aborted")
+		}
+		return nil
+	}
+
+	for _, hostname := range protected {
+		fmt.Fprintf(os.Stderr, "%s is protected. Type its name to continue: ", colors.Hostname.Sprint(hostname))
+		answer, _ := reader.ReadString('\n')
+		if strings.TrimSpace(answer) != hostname {
+			return fmt.Errorf("aborted: confirmation for %s did not match", hostname)
+		}
+	}
+	return nil
+}
@@ -14,26 +14,44 @@ var execInteractive bool
 var execCmd = &cobra.Command{
 	Use:                   hostUsagePattern("exec") + " -- <command> [args...]",
 	Short:                 "Execute command on specified hosts",
-	Long:                  hostLongDescription("Run the specified command with arguments on each host."),
+	Long:                  hostLongDescription("Run the specified command with arguments on each host. With --template the command is rendered as a Go template for each host, with .Host, .Class, .Groups and .Vars available. Asks for confirmation when targeting more hosts than confirm_threshold or any protected host."),
 	DisableFlagParsing:    true,
 	DisableFlagsInUseLine: true,
 	RunE:                  runExec,
+	ValidArgsFunction:     completeExec,
 	SilenceUsage:          true,
 	SilenceErrors:         true,
 }
 
 func init() {
 	execCmd.Flags().BoolVarP(&execInteractive, "interactive", "i", false, "Execute commands with direct stdin/stdout/stderr")
+	execCmd.Flags().BoolP("template", "t", false, "Render the command as a Go template for each host")
+	execCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
 }
 
 func runExec(cmd *cobra.Command, args []string) error {
 	// Manually parse flags since DisableFlagParsing is true
+	args, err := extractGlobalFlags(args)
+	if err != nil {
+		return err
+	}
+
 	isInteractive := false
+	assumeYes := false
+	isTemplate := false
 	filteredArgs := make([]string, 0, len(args))
 
-	for _, arg := range args {
+	for i, arg := range args {
+		if arg == "--" {
+			filteredArgs = append(filteredArgs, args[i:]...)
+			break
+		}
 		if arg == "--interactive" || arg == "-i" {
 			isInteractive = true
+		} else if arg == "--yes" || arg == "-y" {
+			assumeYes = true
+		} else if arg == "--template" || arg == "-t" {
+			isTemplate = true
 		} else {
 			filteredArgs = append(filteredArgs, arg)
 		}
@@ -49,7 +67,7 @@ func runExec(cmd *cobra.Command, args []string) error {
 	}
 
 	if separatorIndex == -1 {
-		return fmt.Errorf("command separator '--' not found. Usage: hladmin exec [-i|--interactive] <hosts...> -- <command> [args...]")
+		return fmt.Errorf("command separator '--' not found. Usage: hladmin exec [-i|--interactive] [-t|--template] [-y|--yes] <hosts...> -- <command> [args...]")
 	}
 
 	if separatorIndex == len(filteredArgs)-1 {
@@ -60,19 +78,36 @@ func runExec(cmd *cobra.Command, args []string) error {
 	command := strings.Join(filteredArgs[separatorIndex+1:], " ")
 
 	// Resolve hosts using helper
-	hostnames, err := resolveHosts(hostArgs)
+	cfg, hostnames, err := resolveTargets(hostArgs)
 	if err != nil {
 		return err
 	}
 
+	// Render the command template for each host, or run it as given
+	commands := make([]string, len(hostnames))
+	for i := range commands {
+		commands[i] = command
+	}
+	if isTemplate {
+		commands, err = renderCommands(cfg, hostnames, command)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Confirm only once the command is known to render for every host
+	if err := confirmTargets(cfg, hostnames, "run '"+command+"'", assumeYes); err != nil {
+		return err
+	}
+
 	// Determine execution mode
 	if isInteractive {
-		if err := executor.ExecuteOnHostsInteractive(hostnames, command); err != nil {
+		if err := executor.ExecuteCommandsInteractive(hostnames, commands); err != nil {
 			return err
 		}
 	} else {
 		var results []executor.Result
-		results, err := executor.ExecuteOnHostsParallelWithProgress(hostnames, command, "Executing command")
+		results, err := executor.ExecuteCommandsParallelWithProgress(hostnames, commands, "Executing command")
 		if err != nil {
 			return err
 		}
@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/template"
 
+	"github.com/claby2/hladmin/internal/colors"
 	"github.com/claby2/hladmin/internal/config"
+	"github.com/claby2/hladmin/internal/executor"
 )
 
 // hostUsagePattern returns a standardized usage pattern for commands that accept hosts
@@ -16,27 +21,128 @@ func hostLongDescription(baseDescription string) string {
 	return fmt.Sprintf("%s Use @group to reference host groups from config.", baseDescription)
 }
 
+// loadHostConfig loads the host configuration, prints any warnings raised by
+// inventory sources to stderr and applies the hosts' connection settings to
+// the executor
+func loadHostConfig() (*config.HostConfig, error) {
+	hostConfig, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load host configuration: %v", err)
+	}
+
+	for _, warning := range hostConfig.Warnings {
+		colors.Warning.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	useConnectionSettings(hostConfig)
+	return hostConfig, nil
+}
+
+// useConnectionSettings makes the executor connect to hosts with their
+// connection settings from cfg
+func useConnectionSettings(cfg *config.HostConfig) {
+	executor.SetSSHOptions(func(host string) []string {
+		return cfg.HostSettings(host).SSHOptions()
+	})
+}
+
 // resolveHosts loads the host configuration and resolves the provided arguments
 // (which may include @group syntax) into a flat list of hostnames.
 // Returns an error if configuration loading fails, host resolution fails,
 // or no hosts are specified/resolved.
 func resolveHosts(args []string) ([]string, error) {
+	_, hostnames, err := resolveTargets(args)
+	return hostnames, err
+}
+
+// resolveTargets is like resolveHosts but also returns the loaded host
+// configuration for commands that need per-host settings. Hosts left out
+// because they are in maintenance are reported on stderr.
+func resolveTargets(args []string) (*config.HostConfig, []string, error) {
+	hostConfig, hostnames, skipped, err := resolveTargetsSkipping(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	printSkippedHosts(hostConfig, skipped)
+
+	// Validate that at least one host is specified
+	if len(hostnames) == 0 {
+		if len(skipped) > 0 {
+			return nil, nil, fmt.Errorf("every selected host is in maintenance mode (use --include-maint or name the hosts explicitly)")
+		}
+		return nil, nil, fmt.Errorf("at least one hostname must be specified")
+	}
+
+	return hostConfig, hostnames, nil
+}
+
+// resolveTargetsSkipping loads the host configuration and resolves args,
+// returning the hosts left out because they are in maintenance separately
+func resolveTargetsSkipping(args []string) (*config.HostConfig, []string, []string, error) {
 	// Load host configuration
-	hostConfig, err := config.LoadConfig()
+	hostConfig, err := loadHostConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load host configuration: %v", err)
+		return nil, nil, nil, err
 	}
 
 	// Resolve host arguments (including @group syntax and defaults)
-	hostnames, err := hostConfig.ResolveHosts(args)
+	hostnames, skipped, err := hostConfig.ResolveHostsSkipping(args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve hosts: %v", err)
+		return nil, nil, nil, fmt.Errorf("failed to resolve hosts: %v", err)
+	}
+	return hostConfig, hostnames, skipped, nil
+}
+
+// printSkippedHosts prints a notice for each host left out because it is in
+// maintenance
+func printSkippedHosts(cfg *config.HostConfig, skipped []string) {
+	for _, host := range skipped {
+		colors.Warning.Fprintf(os.Stderr, "Skipping %s: %s\n", host, maintenanceDescription(cfg.Maintenance[host]))
 	}
+}
 
-	// Validate that at least one host is specified
-	if len(hostnames) == 0 {
-		return nil, fmt.Errorf("at least one hostname must be specified")
+// maintenanceDescription describes a maintenance record in a single line
+func maintenanceDescription(m config.Maintenance) string {
+	description := "in maintenance"
+	if !m.Until.IsZero() {
+		description += " until " + m.Until.Local().Format("2006-01-02 15:04")
+	}
+	if m.Reason != "" {
+		description += " (" + m.Reason + ")"
+	}
+	return description
+}
+
+// renderCommands renders command as a text/template once for each host, with
+// the host's config.HostData as data. Referencing an undefined variable is an
+// error.
+func renderCommands(cfg *config.HostConfig, hostnames []string, command string) ([]string, error) {
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command template: %v", err)
 	}
 
-	return hostnames, nil
+	commands := make([]string, len(hostnames))
+	for i, hostname := range hostnames {
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, cfg.HostData(hostname)); err != nil {
+			return nil, fmt.Errorf("failed to render command for %s: %v", hostname, err)
+		}
+		commands[i] = rendered.String()
+	}
+	return commands, nil
+}
+
+// localRepoPath returns the path of the local repository, which uses the
+// settings of localhost, with a leading ~ and environment variables expanded
+func localRepoPath(cfg *config.HostConfig) (string, error) {
+	path := cfg.Repo("localhost").Path
+	usesHome := path == "~" || strings.HasPrefix(path, "~/") || strings.Contains(path, "$HOME")
+	if usesHome && os.Getenv("HOME") == "" {
+		return "", fmt.Errorf("HOME environment variable not set")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = "$HOME" + path[1:]
+	}
+	return os.ExpandEnv(path), nil
 }
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/config"
+	"github.com/claby2/hladmin/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Manage cached inventory information",
+	Long:  "Inspect and update inventory information that hladmin gathers from hosts and inventory sources.",
+}
+
+var inventoryRefreshCmd = &cobra.Command{
+	Use:               hostUsagePattern("refresh"),
+	Short:             "Refresh the cached HOSTCLASS of each host",
+	Long:              hostLongDescription("Query $HOSTCLASS on each host and store the result in the class cache used by @class:<name> selectors. Without arguments, every host in the configuration is queried. Dynamic inventory sources are queried again instead of using cached results."),
+	RunE:              runInventoryRefresh,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var inventoryListCmd = &cobra.Command{
+	Use:           "list",
+	Short:         "List named inventories",
+	Long:          "List the named inventories in the inventories directory that can be selected with --inventory or HLADMIN_INVENTORY.",
+	Args:          cobra.NoArgs,
+	RunE:          runInventoryList,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var inventoryExportCmd = &cobra.Command{
+	Use:           "export",
+	Short:         "Export the inventory for other tools",
+	Long:          "Print the loaded inventory in a format understood by other tools. Groups are kept, including nested groups where the format supports them, and hosts carry their resolved variables where the format allows. Supported formats: " + strings.Join(config.ExportFormats, ", ") + ".",
+	Args:          cobra.NoArgs,
+	RunE:          runInventoryExport,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var inventoryImportCmd = &cobra.Command{
+	Use:           "import <ansible-inventory>",
+	Short:         "Convert an Ansible INI inventory to hladmin config",
+	Long:          "Read an Ansible INI inventory and print the equivalent group and var directives. Use - to read from standard input. Redirect the output to a file in the hosts.d directory to use it.",
+	Args:          cobra.ExactArgs(1),
+	RunE:          runInventoryImport,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var exportFormat string
+
+func init() {
+	inventoryExportCmd.Flags().StringVarP(&exportFormat, "format", "f", "ansible-ini", "Output format ("+strings.Join(config.ExportFormats, ", ")+")")
+	inventoryExportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(config.ExportFormats, cobra.ShellCompDirectiveNoFileComp))
+
+	inventoryCmd.AddCommand(inventoryRefreshCmd)
+	inventoryCmd.AddCommand(inventoryListCmd)
+	inventoryCmd.AddCommand(inventoryExportCmd)
+	inventoryCmd.AddCommand(inventoryImportCmd)
+}
+
+func runInventoryExport(cmd *cobra.Command, args []string) error {
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return err
+	}
+	return cfg.Export(os.Stdout, exportFormat)
+}
+
+func runInventoryImport(cmd *cobra.Command, args []string) error {
+	input := os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open inventory: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	directives, warnings, err := config.ImportAnsibleINI(input)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, colors.Warning.Sprintf("Warning: %s", warning))
+	}
+	fmt.Print(directives)
+	return nil
+}
+
+func runInventoryList(cmd *cobra.Command, args []string) error {
+	names, err := config.ListInventories()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		colors.Warning.Printf("No inventories found in %s\n", config.GetInventoriesDir())
+		return nil
+	}
+
+	active := config.ActiveInventory()
+	for _, name := range names {
+		if name == active {
+			fmt.Printf("%s %s\n", colors.Success.Sprint("*"), colors.Bold.Sprint(name))
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	return nil
+}
+
+func runInventoryRefresh(cmd *cobra.Command, args []string) error {
+	// Query inventory sources again rather than reusing cached results
+	if err := config.ClearInventoryCache(); err != nil {
+		return err
+	}
+
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return err
+	}
+
+	var hostnames []string
+	if len(args) == 0 {
+		hostnames = cfg.AllHosts()
+	} else {
+		hostnames, err = cfg.ResolveHosts(args)
+		if err != nil {
+			return fmt.Errorf("failed to resolve hosts: %v", err)
+		}
+	}
+	if len(hostnames) == 0 {
+		return fmt.Errorf("at least one hostname must be specified")
+	}
+
+	results, err := executor.ExecuteOnHostsParallelWithProgress(hostnames, "echo \"$HOSTCLASS\"", "Querying HOSTCLASS")
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%s %s\n", colors.Hostname.Sprint(result.Hostname), colors.Error.Sprintf("%v (keeping cached class)", result.Err))
+			continue
+		}
+
+		class := strings.TrimSpace(result.Stdout)
+		if class == "" {
+			delete(cfg.Classes, result.Hostname)
+			fmt.Printf("%s %s\n", colors.Hostname.Sprint(result.Hostname), colors.Warning.Sprint("HOSTCLASS is not set"))
+			continue
+		}
+
+		cfg.Classes[result.Hostname] = class
+		fmt.Printf("%s %s\n", colors.Hostname.Sprint(result.Hostname), class)
+	}
+
+	if err := config.SaveClasses(cfg.Classes); err != nil {
+		return err
+	}
+
+	for _, conflict := range cfg.ClassConflicts() {
+		colors.Warning.Printf("Warning: %s\n", conflict)
+	}
+
+	return executor.ResultsError(results)
+}
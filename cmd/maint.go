@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var maintReason string
+var maintUntil string
+
+var maintCmd = &cobra.Command{
+	Use:   "maint",
+	Short: "Put hosts in and out of maintenance mode",
+	Long:  "Fence hosts off while they are being repaired. Hosts in maintenance are left out when groups are expanded, unless they are named explicitly or --include-maint is given. Maintenance state is stored locally.",
+}
+
+var maintOnCmd = &cobra.Command{
+	Use:               hostUsagePattern("on"),
+	Short:             "Put hosts in maintenance mode",
+	Long:              hostLongDescription("Put hosts in maintenance mode. --until accepts a duration such as 2h or 3d12h, or a time such as 2006-01-02 or 2006-01-02 15:04; without it maintenance lasts until it is turned off."),
+	Args:              cobra.MinimumNArgs(1),
+	RunE:              runMaintOn,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var maintOffCmd = &cobra.Command{
+	Use:               hostUsagePattern("off"),
+	Short:             "Take hosts out of maintenance mode",
+	Args:              cobra.MinimumNArgs(1),
+	RunE:              runMaintOff,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var maintListCmd = &cobra.Command{
+	Use:           "list",
+	Short:         "List hosts in maintenance mode",
+	Args:          cobra.NoArgs,
+	RunE:          runMaintList,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	maintOnCmd.Flags().StringVar(&maintReason, "reason", "", "Why the hosts are in maintenance")
+	maintOnCmd.Flags().StringVar(&maintUntil, "until", "", "When maintenance ends, as a duration or a time")
+
+	maintCmd.AddCommand(maintOnCmd)
+	maintCmd.AddCommand(maintOffCmd)
+	maintCmd.AddCommand(maintListCmd)
+}
+
+// parseUntil parses the --until flag as a duration from now or as a local
+// date and time
+func parseUntil(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := parseDuration(value); err == nil {
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("--until must be in the future: %s", value)
+		}
+		return now.Add(duration), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		until, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if !until.After(now) {
+			return time.Time{}, fmt.Errorf("--until must be in the future: %s", value)
+		}
+		return until, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --until value '%s': expected a duration such as 2h or 3d, or a time such as 2006-01-02 15:04", value)
+}
+
+// parseDuration is time.ParseDuration with support for a leading number of
+// days, as in 3d or 1d12h
+func parseDuration(value string) (time.Duration, error) {
+	days, rest, found := strings.Cut(value, "d")
+	if !found {
+		return time.ParseDuration(value)
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+	duration := time.Duration(n) * 24 * time.Hour
+	if rest == "" {
+		return duration, nil
+	}
+	extra, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, err
+	}
+	return duration + extra, nil
+}
+
+// resolveMaintTargets resolves args for the maint commands, which always
+// include hosts that are already in maintenance
+func resolveMaintTargets(args []string) (*config.HostConfig, []string, error) {
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.IncludeMaintenance = true
+
+	hostnames, err := cfg.ResolveHosts(args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve hosts: %v", err)
+	}
+	return cfg, hostnames, nil
+}
+
+func runMaintOn(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	until, err := parseUntil(maintUntil, now)
+	if err != nil {
+		return err
+	}
+
+	cfg, hostnames, err := resolveMaintTargets(args)
+	if err != nil {
+		return err
+	}
+
+	for _, hostname := range hostnames {
+		entry := config.Maintenance{Reason: maintReason, Since: now, Until: until}
+		if existing, ok := cfg.Maintenance[hostname]; ok {
+			entry.Since = existing.Since
+		}
+		cfg.Maintenance[hostname] = entry
+	}
+	if err := config.SaveMaintenance(cfg.Maintenance); err != nil {
+		return err
+	}
+
+	for _, hostname := range hostnames {
+		fmt.Printf("%s %s\n", colors.Hostname.Sprint(hostname), colors.Warning.Sprint(maintenanceDescription(cfg.Maintenance[hostname])))
+	}
+	return nil
+}
+
+func runMaintOff(cmd *cobra.Command, args []string) error {
+	cfg, hostnames, err := resolveMaintTargets(args)
+	if err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, hostname := range hostnames {
+		if _, ok := cfg.Maintenance[hostname]; ok {
+			delete(cfg.Maintenance, hostname)
+			removed = append(removed, hostname)
+		}
+	}
+	if err := config.SaveMaintenance(cfg.Maintenance); err != nil {
+		return err
+	}
+
+	if len(removed) == 0 {
+		colors.Warning.Println("None of the hosts are in maintenance mode.")
+		return nil
+	}
+	for _, hostname := range removed {
+		fmt.Printf("%s %s\n", colors.Hostname.Sprint(hostname), colors.Success.Sprint("out of maintenance"))
+	}
+	return nil
+}
+
+func runMaintList(cmd *cobra.Command, args []string) error {
+	maintenance, err := config.LoadMaintenance()
+	if err != nil {
+		return err
+	}
+	if len(maintenance) == 0 {
+		colors.Info.Println("No hosts are in maintenance mode.")
+		return nil
+	}
+
+	hostnames := make([]string, 0, len(maintenance))
+	for hostname := range maintenance {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
+	fmt.Fprintf(w, "HOSTNAME\tSINCE\tUNTIL\tREASON\n")
+	for _, hostname := range hostnames {
+		entry := maintenance[hostname]
+		until := "-"
+		if !entry.Until.IsZero() {
+			until = entry.Until.Local().Format("2006-01-02 15:04")
+		}
+		reason := entry.Reason
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", hostname, entry.Since.Local().Format("2006-01-02 15:04"), until, reason)
+	}
+	return w.Flush()
+}
@@ -1,29 +1,36 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/claby2/hladmin/internal/executor"
 	"github.com/spf13/cobra"
 )
 
 var pullCmd = &cobra.Command{
-	Use:           hostUsagePattern("pull"),
-	Short:         "Run git pull on specified hosts",
-	Long:          hostLongDescription("Execute git pull in $HOME/nix-config on each host."),
-	RunE:          runPull,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+	Use:               hostUsagePattern("pull"),
+	Short:             "Run git pull on specified hosts",
+	Long:              hostLongDescription("Execute git pull in each host's configuration repository ($HOME/nix-config unless overridden in the config)."),
+	RunE:              runPull,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
 }
 
 func runPull(cmd *cobra.Command, args []string) error {
-	hostnames, err := resolveHosts(args)
+	cfg, hostnames, err := resolveTargets(args)
 	if err != nil {
 		return err
 	}
 
-	command := "cd $HOME/nix-config && git pull"
+	commands := make([]string, len(hostnames))
+	for i, hostname := range hostnames {
+		repo := cfg.Repo(hostname)
+		commands[i] = fmt.Sprintf("cd %s && %s", repo.Path, repo.PullCommand())
+	}
 
 	var results []executor.Result
-	results, err = executor.ExecuteOnHostsParallelWithProgress(hostnames, command, "Running git pull")
+	results, err = executor.ExecuteCommandsParallelWithProgress(hostnames, commands, "Running git pull")
 	if err != nil {
 		return err
 	}
@@ -4,39 +4,41 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/executor"
 	"github.com/spf13/cobra"
 )
 
 var dryRun bool
+var pushYes bool
 
 var pushStagedCmd = &cobra.Command{
-	Use:           hostUsagePattern("push-staged"),
-	Short:         "Push staged git changes to specified hosts",
-	Long:          hostLongDescription("Check for staged changes in $HOME/nix-config and apply them to clean hosts."),
-	RunE:          runPushStaged,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+	Use:               hostUsagePattern("push-staged"),
+	Short:             "Push staged git changes to specified hosts",
+	Long:              hostLongDescription("Check for staged changes in the local configuration repository and apply them to clean hosts. Repository locations default to $HOME/nix-config and can be overridden per host in the config, with localhost selecting the local one."),
+	RunE:              runPushStaged,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
 }
 
 func init() {
 	pushStagedCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what would be done without making changes")
+	pushStagedCmd.Flags().BoolVarP(&pushYes, "yes", "y", false, "Do not ask for confirmation")
 }
 
 func runPushStaged(cmd *cobra.Command, args []string) error {
-	hostnames, err := resolveHosts(args)
+	cfg, hostnames, err := resolveTargets(args)
 	if err != nil {
 		return err
 	}
 
-	homeDir := os.Getenv("HOME")
-	if homeDir == "" {
-		return fmt.Errorf("HOME environment variable not set")
+	nixConfigPath, err := localRepoPath(cfg)
+	if err != nil {
+		return err
 	}
-	nixConfigPath := filepath.Join(homeDir, "nix-config")
 
 	// Check for staged changes with --binary flag to handle binary files properly
 	diffCmd := exec.Command("git", "diff", "--cached", "--binary")
@@ -55,6 +57,8 @@ func runPushStaged(cmd *cobra.Command, args []string) error {
 		colors.Header.Println("Staged changes:")
 		fmt.Println(string(diffOutput))
 		fmt.Println()
+	} else if err := confirmTargets(cfg, hostnames, "apply staged changes", pushYes); err != nil {
+		return err
 	}
 
 	// Create temporary patch file
@@ -74,8 +78,10 @@ func runPushStaged(cmd *cobra.Command, args []string) error {
 	for _, hostname := range hostnames {
 		fmt.Printf("%s %s\n", colors.Info.Sprint("Processing host:"), colors.Hostname.Sprint(hostname))
 
+		remoteRepoPath := cfg.Repo(hostname).Path
+
 		// Check if remote repo is clean
-		cleanCmd := exec.Command("ssh", hostname, "cd $HOME/nix-config && git status --porcelain")
+		cleanCmd := executor.SSHCommand(hostname, fmt.Sprintf("cd %s && git status --porcelain", remoteRepoPath))
 		cleanOutput, err := cleanCmd.CombinedOutput()
 		if err != nil {
 			colors.Error.Printf("  Error checking git status on %s: %v\n", hostname, err)
@@ -101,18 +107,18 @@ func runPushStaged(cmd *cobra.Command, args []string) error {
 		remotePatchFile := fmt.Sprintf("/tmp/hladmin-patch-%s-%d.patch", hostname, os.Getpid())
 
 		// Copy patch to remote
-		copyCmd := exec.Command("scp", patchFile.Name(), fmt.Sprintf("%s:%s", hostname, remotePatchFile))
+		copyCmd := executor.SCPCommand(hostname, patchFile.Name(), remotePatchFile)
 		if err := copyCmd.Run(); err != nil {
 			colors.Error.Printf("  Error copying patch: %v\n", err)
 			continue
 		}
 
 		// Apply patch - separate from cleanup to properly check git apply result
-		applyCmd := exec.Command("ssh", hostname, fmt.Sprintf("cd $HOME/nix-config && git apply %s", remotePatchFile))
+		applyCmd := executor.SSHCommand(hostname, fmt.Sprintf("cd %s && git apply %s", remoteRepoPath, remotePatchFile))
 		applyOutput, err := applyCmd.CombinedOutput()
 
 		// Always cleanup the remote patch file, regardless of git apply result
-		cleanupCmd := exec.Command("ssh", hostname, fmt.Sprintf("rm -f %s", remotePatchFile))
+		cleanupCmd := executor.SSHCommand(hostname, fmt.Sprintf("rm -f %s", remotePatchFile))
 		cleanupCmd.Run()
 
 		// Check git apply result after cleanup
@@ -1,28 +1,44 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/claby2/hladmin/internal/executor"
 	"github.com/spf13/cobra"
 )
 
 var rebuildCmd = &cobra.Command{
-	Use:           hostUsagePattern("rebuild"),
-	Short:         "Run rebuild script on specified hosts",
-	Long:          hostLongDescription("Execute the rebuild.sh script in $HOME/nix-config on each host."),
-	RunE:          runRebuild,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+	Use:               hostUsagePattern("rebuild"),
+	Short:             "Run rebuild script on specified hosts",
+	Long:              hostLongDescription("Execute the rebuild command in each host's configuration repository (./rebuild.sh in $HOME/nix-config unless overridden in the config). Asks for confirmation when targeting more hosts than confirm_threshold or any protected host."),
+	RunE:              runRebuild,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var rebuildYes bool
+
+func init() {
+	rebuildCmd.Flags().BoolVarP(&rebuildYes, "yes", "y", false, "Do not ask for confirmation")
 }
 
 func runRebuild(cmd *cobra.Command, args []string) error {
-	hostnames, err := resolveHosts(args)
+	cfg, hostnames, err := resolveTargets(args)
 	if err != nil {
 		return err
 	}
+	if err := confirmTargets(cfg, hostnames, "run rebuild", rebuildYes); err != nil {
+		return err
+	}
 
-	command := "cd $HOME/nix-config && ./rebuild.sh"
+	commands := make([]string, len(hostnames))
+	for i, hostname := range hostnames {
+		repo := cfg.Repo(hostname)
+		commands[i] = fmt.Sprintf("cd %s && %s", repo.Path, repo.Rebuild)
+	}
 
-	if err := executor.ExecuteOnHostsInteractive(hostnames, command); err != nil {
+	if err := executor.ExecuteCommandsInteractive(hostnames, commands); err != nil {
 		return err
 	}
 	return nil
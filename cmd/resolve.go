@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/claby2/hladmin/internal/colors"
@@ -11,20 +13,33 @@ import (
 )
 
 var resolveCmd = &cobra.Command{
-	Use:           hostUsagePattern("resolve"),
-	Short:         "Show host configuration and resolve groups",
-	Long:          hostLongDescription("Show the current host configuration and resolve group references. Without arguments, displays the full configuration including all groups and the default group. With arguments, shows how the specified hosts and groups resolve to individual hostnames."),
-	RunE:          runResolve,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+	Use:               hostUsagePattern("resolve"),
+	Short:             "Show host configuration and resolve groups",
+	Long:              hostLongDescription("Show the current host configuration and resolve group references. Without arguments, displays the full configuration including all groups and the default group. With arguments, shows how the specified hosts and groups resolve to individual hostnames. Use --member-of to list the groups containing a host and --json for machine-readable output."),
+	RunE:              runResolve,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var resolveMemberOf string
+var resolveJSON bool
+
+func init() {
+	resolveCmd.Flags().StringVar(&resolveMemberOf, "member-of", "", "List the groups that contain the given host, directly or through nesting")
+	resolveCmd.Flags().BoolVar(&resolveJSON, "json", false, "Print the configuration and resolution as JSON")
 }
 
 func runResolve(cmd *cobra.Command, args []string) error {
-	cfg, err := config.LoadConfig()
+	cfg, err := loadHostConfig()
 	if err != nil {
 		return err
 	}
 
+	if resolveJSON {
+		return printResolveJSON(cfg, args)
+	}
+
 	configPath := config.GetConfigPath()
 	if configPath == "" {
 		configPath = "unknown"
@@ -35,10 +50,27 @@ func runResolve(cmd *cobra.Command, args []string) error {
 	}
 
 	// Always show config location first
+	if name := config.ActiveInventory(); name != "" {
+		fmt.Printf("%s %s\n", colors.Info.Sprint("Inventory:"), colors.Bold.Sprint(name))
+	}
 	if configExists {
-		fmt.Printf("%s %s\n\n", colors.Info.Sprint("Config:"), configPath)
+		fmt.Printf("%s %s\n", colors.Info.Sprint("Config:"), configPath)
 	} else {
-		fmt.Printf("%s %s (checked %s)\n\n", colors.Info.Sprint("Config:"), colors.Warning.Sprint("No configuration file found"), configPath)
+		fmt.Printf("%s %s (checked %s)\n", colors.Info.Sprint("Config:"), colors.Warning.Sprint("No configuration file found"), configPath)
+	}
+	for _, file := range cfg.Files() {
+		if file != configPath {
+			fmt.Printf("%s %s\n", colors.Info.Sprint("Included:"), file)
+		}
+	}
+	fmt.Println()
+
+	if resolveMemberOf != "" {
+		showMembership(cfg, resolveMemberOf)
+		if len(args) == 0 {
+			return nil
+		}
+		fmt.Println()
 	}
 
 	// If no arguments, show full configuration
@@ -60,7 +92,8 @@ func showFullConfiguration(cfg *config.HostConfig) {
 		colors.Warning.Println("No groups defined.")
 	} else {
 		colors.Header.Println("Groups:")
-		for groupName, hosts := range cfg.Groups {
+		for _, groupName := range cfg.GroupNames() {
+			hosts := cfg.Groups[groupName]
 			fmt.Printf("  %s: %s\n", colors.Bold.Sprintf("@%s", groupName), strings.Join(hosts, ", "))
 		}
 		fmt.Println()
@@ -71,10 +104,63 @@ func showFullConfiguration(cfg *config.HostConfig) {
 			fmt.Printf("%s %s\n", colors.Info.Sprint("Default Group:"), colors.Secondary.Sprint("none"))
 		}
 	}
+
+	if ungrouped := ungroupedHosts(cfg); len(ungrouped) > 0 {
+		fmt.Println()
+		fmt.Printf("%s %s\n", colors.Info.Sprint("Ungrouped Hosts:"), strings.Join(ungrouped, ", "))
+	}
+
+	if len(cfg.Classes) > 0 {
+		fmt.Println()
+		showClasses(cfg)
+	}
+}
+
+// ungroupedHosts returns hosts discovered by inventory sources that are not
+// members of any group
+func ungroupedHosts(cfg *config.HostConfig) []string {
+	grouped := make(map[string]bool)
+	for _, hosts := range cfg.Groups {
+		for _, host := range hosts {
+			grouped[host] = true
+		}
+	}
+
+	var ungrouped []string
+	for _, host := range cfg.Hosts {
+		if !grouped[host] {
+			ungrouped = append(ungrouped, host)
+		}
+	}
+	return ungrouped
+}
+
+func showClasses(cfg *config.HostConfig) {
+	hostsByClass := make(map[string][]string)
+	for host, class := range cfg.Classes {
+		hostsByClass[class] = append(hostsByClass[class], host)
+	}
+
+	classNames := make([]string, 0, len(hostsByClass))
+	for class := range hostsByClass {
+		classNames = append(classNames, class)
+	}
+	sort.Strings(classNames)
+
+	colors.Header.Println("Classes:")
+	for _, class := range classNames {
+		hosts := hostsByClass[class]
+		sort.Strings(hosts)
+		fmt.Printf("  %s: %s\n", colors.Bold.Sprintf("@class:%s", class), strings.Join(hosts, ", "))
+	}
+
+	for _, conflict := range cfg.ClassConflicts() {
+		colors.Warning.Printf("Warning: %s\n", conflict)
+	}
 }
 
 func showHostResolution(cfg *config.HostConfig, args []string) error {
-	resolvedHosts, err := cfg.ResolveHosts(args)
+	resolvedHosts, skipped, err := cfg.ResolveHostsSkipping(args)
 	if err != nil {
 		return err
 	}
@@ -82,11 +168,10 @@ func showHostResolution(cfg *config.HostConfig, args []string) error {
 	// Show individual resolutions
 	for _, arg := range args {
 		if strings.HasPrefix(arg, "@") {
-			groupName := arg[1:]
-			if hosts, exists := cfg.Groups[groupName]; exists {
+			if hosts, err := cfg.ExpandSelector(arg); err == nil {
 				fmt.Printf("%s -> %s\n", colors.Bold.Sprint(arg), strings.Join(hosts, ", "))
 			} else {
-				fmt.Printf("%s -> %s\n", colors.Bold.Sprint(arg), colors.Error.Sprint("error: unknown group"))
+				fmt.Printf("%s -> %s\n", colors.Bold.Sprint(arg), colors.Error.Sprintf("error: %v", err))
 			}
 		} else {
 			fmt.Printf("%s -> %s\n", colors.Hostname.Sprint(arg), arg)
@@ -95,5 +180,165 @@ func showHostResolution(cfg *config.HostConfig, args []string) error {
 
 	fmt.Println()
 	fmt.Printf("%s %s\n", colors.Info.Sprint("Final host list:"), strings.Join(resolvedHosts, ", "))
+	for _, host := range skipped {
+		fmt.Printf("%s %s %s\n", colors.Warning.Sprint("Skipped:"), colors.Hostname.Sprint(host), colors.Secondary.Sprint(maintenanceDescription(cfg.Maintenance[host])))
+	}
+	return nil
+}
+
+func showMembership(cfg *config.HostConfig, host string) {
+	memberships := cfg.MemberOf(host)
+	if len(memberships) == 0 {
+		colors.Warning.Printf("%s is not a member of any group.\n", host)
+		return
+	}
+
+	fmt.Printf("%s %s\n", colors.Hostname.Sprint(host), colors.Info.Sprint("is a member of:"))
+	for _, membership := range memberships {
+		if membership.Via == "" {
+			fmt.Printf("  %s\n", colors.Bold.Sprintf("@%s", membership.Group))
+		} else {
+			fmt.Printf("  %s %s\n", colors.Bold.Sprintf("@%s", membership.Group), colors.Secondary.Sprintf("(via @%s)", membership.Via))
+		}
+	}
+}
+
+// resolveOutput is the JSON document printed by resolve --json. Maps are
+// encoded with sorted keys, so the output is stable.
+type resolveOutput struct {
+	Config       string                  `json:"config"`
+	Inventory    string                  `json:"inventory,omitempty"`
+	Files        []string                `json:"files"`
+	DefaultGroup string                  `json:"default_group,omitempty"`
+	Groups       map[string]resolveGroup `json:"groups"`
+	Hosts        map[string]resolveHost  `json:"hosts"`
+	Resolution   *resolveResolution      `json:"resolution,omitempty"`
+	MemberOf     *resolveMemberOfOutput  `json:"member_of,omitempty"`
+}
+
+type resolveGroup struct {
+	Members []string `json:"members"`
+	Hosts   []string `json:"hosts"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type resolveHost struct {
+	Class      string            `json:"class,omitempty"`
+	Groups     []string          `json:"groups"`
+	Vars       map[string]string `json:"vars"`
+	Repo       resolveRepo       `json:"repo"`
+	Protected  bool              `json:"protected,omitempty"`
+	Connection resolveConnection `json:"connection"`
+	// Maintenance is set when the host is in maintenance mode
+	Maintenance *config.Maintenance `json:"maintenance,omitempty"`
+}
+
+type resolveRepo struct {
+	Path    string `json:"path"`
+	Rebuild string `json:"rebuild"`
+	Remote  string `json:"remote,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+}
+
+type resolveConnection struct {
+	Address   string   `json:"address,omitempty"`
+	User      string   `json:"user,omitempty"`
+	Port      int      `json:"port,omitempty"`
+	Identity  string   `json:"identity,omitempty"`
+	ProxyJump string   `json:"proxy_jump,omitempty"`
+	Options   []string `json:"options,omitempty"`
+}
+
+type resolveResolution struct {
+	Args      []string            `json:"args"`
+	Selectors map[string][]string `json:"selectors"`
+	Hosts     []string            `json:"hosts"`
+	Skipped   []string            `json:"skipped,omitempty"`
+}
+
+type resolveMemberOfOutput struct {
+	Host   string              `json:"host"`
+	Groups []resolveMembership `json:"groups"`
+}
+
+type resolveMembership struct {
+	Group string `json:"group"`
+	Via   string `json:"via,omitempty"`
+}
+
+func printResolveJSON(cfg *config.HostConfig, args []string) error {
+	output := resolveOutput{
+		Config:       config.GetConfigPath(),
+		Inventory:    config.ActiveInventory(),
+		Files:        append([]string{}, cfg.Files()...),
+		DefaultGroup: cfg.DefaultGroup,
+		Groups:       make(map[string]resolveGroup),
+		Hosts:        make(map[string]resolveHost),
+	}
+
+	for _, name := range cfg.GroupNames() {
+		group := resolveGroup{Members: cfg.Groups[name], Hosts: []string{}}
+		if hosts, err := cfg.ExpandSelector("@" + name); err == nil {
+			group.Hosts = hosts
+		} else {
+			group.Error = err.Error()
+		}
+		output.Groups[name] = group
+	}
+
+	hosts := cfg.AllHosts()
+	if len(args) > 0 {
+		resolved, skipped, err := cfg.ResolveHostsSkipping(args)
+		if err != nil {
+			return err
+		}
+
+		resolution := &resolveResolution{Args: args, Selectors: make(map[string][]string), Hosts: resolved, Skipped: skipped}
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "@") {
+				resolution.Selectors[arg], _ = cfg.ExpandSelector(arg)
+			}
+		}
+		output.Resolution = resolution
+		hosts = append(hosts, resolved...)
+	}
+
+	for _, host := range hosts {
+		repo := cfg.Repo(host)
+		settings := cfg.HostSettings(host)
+		entry := resolveHost{
+			Class:     cfg.Classes[host],
+			Groups:    append([]string{}, cfg.GroupsOf(host)...),
+			Vars:      cfg.Vars(host),
+			Repo:      resolveRepo{Path: repo.Path, Rebuild: repo.Rebuild, Remote: repo.Remote, Branch: repo.Branch},
+			Protected: settings.Protected,
+			Connection: resolveConnection{
+				Address:   settings.Address,
+				User:      settings.User,
+				Port:      settings.Port,
+				Identity:  settings.Identity,
+				ProxyJump: settings.ProxyJump,
+				Options:   settings.Options,
+			},
+		}
+		if maintenance, ok := cfg.Maintenance[host]; ok {
+			entry.Maintenance = &maintenance
+		}
+		output.Hosts[host] = entry
+	}
+
+	if resolveMemberOf != "" {
+		memberOf := &resolveMemberOfOutput{Host: resolveMemberOf, Groups: []resolveMembership{}}
+		for _, membership := range cfg.MemberOf(resolveMemberOf) {
+			memberOf.Groups = append(memberOf.Groups, resolveMembership{Group: membership.Group, Via: membership.Via})
+		}
+		output.MemberOf = memberOf
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %v", err)
+	}
+	fmt.Println(string(data))
 	return nil
 }
@@ -1,13 +1,34 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/config"
 	"github.com/spf13/cobra"
 )
 
+var configPath string
+var inventoryName string
+var includeMaint bool
+
 var rootCmd = &cobra.Command{
-	Use:   "hladmin",
-	Short: "Homelab administration tool",
-	Long:  "A tool for managing homelab servers running NixOS and macOS with nix-darwin",
+	Use:               "hladmin",
+	Short:             "Homelab administration tool",
+	Long:              "A tool for managing homelab servers running NixOS and macOS with nix-darwin",
+	PersistentPreRunE: applyGlobalFlags,
+}
+
+// ExitError makes hladmin exit with Code without printing an error. It is
+// returned by commands whose exit status carries meaning and that have
+// already printed their output.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 func Execute() error {
@@ -15,10 +36,80 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the host configuration file (overrides HLADMIN_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&inventoryName, "inventory", "", "Name of the inventory to use from the inventories directory (overrides HLADMIN_INVENTORY)")
+	rootCmd.PersistentFlags().BoolVar(&includeMaint, "include-maint", false, "Keep hosts in maintenance mode when expanding groups")
+	rootCmd.RegisterFlagCompletionFunc("inventory", completeInventories)
+
 	rootCmd.AddCommand(pushStagedCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(rebuildCmd)
 	rootCmd.AddCommand(pullCmd)
 	rootCmd.AddCommand(execCmd)
 	rootCmd.AddCommand(resolveCmd)
+	rootCmd.AddCommand(inventoryCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(maintCmd)
+	rootCmd.AddCommand(serveMetricsCmd)
+}
+
+// applyGlobalFlags passes the global config selection and maintenance flags to
+// the config package
+func applyGlobalFlags(cmd *cobra.Command, args []string) error {
+	if configPath != "" && inventoryName != "" {
+		return fmt.Errorf("--config and --inventory cannot be used together")
+	}
+
+	config.SetConfigPath(configPath)
+	config.SetInventory(inventoryName)
+	config.SetIncludeMaintenance(includeMaint)
+	return config.CheckInventory()
+}
+
+// extractGlobalFlags applies the global flags found in args before the "--"
+// separator and returns the remaining arguments. It is used by commands that
+// disable cobra's flag parsing.
+func extractGlobalFlags(args []string) ([]string, error) {
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name == "--include-maint" {
+			include, err := strconv.ParseBool(value)
+			if !hasValue {
+				include, err = true, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for --include-maint: %s", value)
+			}
+			includeMaint = include
+			continue
+		}
+		if name != "--config" && name != "--inventory" {
+			remaining = append(remaining, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag needs an argument: %s", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		if name == "--config" {
+			configPath = value
+		} else {
+			inventoryName = value
+		}
+	}
+
+	if err := applyGlobalFlags(nil, nil); err != nil {
+		return nil, err
+	}
+	return remaining, nil
 }
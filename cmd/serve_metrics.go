@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var serveMetricsCmd = &cobra.Command{
+	Use:               hostUsagePattern("serve-metrics"),
+	Short:             "Serve status probe results as Prometheus metrics",
+	Long:              hostLongDescription("Run the status probe on the hosts every interval and serve the results on /metrics in the Prometheus text format. The configuration and maintenance state are reloaded before every probe."),
+	RunE:              runServeMetrics,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var metricsListen string
+var metricsInterval time.Duration
+var metricsTimeout time.Duration
+var metricsConcurrency int
+
+func init() {
+	serveMetricsCmd.Flags().StringVar(&metricsListen, "listen", ":9101", "Address to serve metrics on")
+	serveMetricsCmd.Flags().DurationVar(&metricsInterval, "interval", time.Minute, "Time between probes")
+	serveMetricsCmd.Flags().DurationVar(&metricsTimeout, "timeout", 30*time.Second, "Time after which the probe of a host is abandoned")
+	serveMetricsCmd.Flags().IntVar(&metricsConcurrency, "concurrency", 10, "Number of hosts probed at once")
+}
+
+// metricsProbes are the status probes whose results are exported
+var metricsProbes = []string{"hostclass", "version", "repo", "disk", "mem", "load", "_repo_dirty"}
+
+func runServeMetrics(cmd *cobra.Command, args []string) error {
+	if metricsInterval <= 0 || metricsTimeout <= 0 || metricsConcurrency <= 0 {
+		return fmt.Errorf("--interval, --timeout and --concurrency must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Listen before the first probe so a taken address fails right away
+	listener, err := net.Listen("tcp", metricsListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", metricsListen, err)
+	}
+
+	exporter := &metricsExporter{}
+	if err := exporter.probe(args); err != nil {
+		listener.Close()
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(metricsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := exporter.probe(args); err != nil {
+					colors.Error.Fprintf(os.Stderr, "Probe failed: %v\n", err)
+				}
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve metrics: %v", err)
+	}
+	return nil
+}
+
+// metricsExporter serves the metrics of the last completed probe
+type metricsExporter struct {
+	mu      sync.Mutex
+	metrics []byte
+}
+
+func (e *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	metrics := e.metrics
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(metrics)
+}
+
+// probe resolves args, probes the hosts and replaces the served metrics. The
+// previous metrics are kept when resolving fails.
+func (e *metricsExporter) probe(args []string) error {
+	cfg, hostnames, skipped, err := resolveTargetsSkipping(args)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var hosts []hostInfo
+	if len(hostnames) > 0 {
+		if hosts, err = collectHostInfo(cfg, hostnames, metricsProbes, false, executor.Limits{Concurrency: metricsConcurrency, Timeout: metricsTimeout}); err != nil {
+			return err
+		}
+	}
+
+	// Drift is only exported when the local repository can be read
+	local, err := openLocalRepo(cfg)
+	if err != nil {
+		colors.Warning.Fprintf(os.Stderr, "Warning: not exporting drift: %v\n", err)
+		local = nil
+	}
+
+	m := &metricsWriter{}
+	m.gauge("hladmin_host_up", "Whether the status probe of the host succeeded")
+	for _, host := range hosts {
+		m.sample(host.hostname, nil, boolValue(host.err == ""))
+	}
+	m.gauge("hladmin_probe_duration_seconds", "Time taken to probe the host")
+	for _, host := range hosts {
+		m.sample(host.hostname, nil, host.duration.Seconds())
+	}
+	m.gauge("hladmin_host_info", "Class and deployed configuration revision of the host")
+	for _, host := range hosts {
+		// A host whose revision could not be read has no info rather than an
+		// empty version
+		if host.err == "" && host.metrics["version"].err == "" {
+			m.sample(host.hostname, []string{"hostclass", host.metrics["hostclass"].value, "version", host.metrics["version"].value}, 1)
+		}
+	}
+	m.gauge("hladmin_disk_usage_ratio", "Fraction of the root filesystem in use")
+	for _, host := range hosts {
+		if value, ok := host.ratio("disk"); ok {
+			m.sample(host.hostname, nil, value)
+		}
+	}
+	m.gauge("hladmin_memory_usage_ratio", "Fraction of memory in use")
+	for _, host := range hosts {
+		if value, ok := host.ratio("mem"); ok {
+			m.sample(host.hostname, nil, value)
+		}
+	}
+	for i, name := range []string{"hladmin_load1", "hladmin_load5", "hladmin_load15"} {
+		m.gauge(name, fmt.Sprintf("Load average over %s", []string{"1 minute", "5 minutes", "15 minutes"}[i]))
+		for _, host := range hosts {
+			if host.err != "" || host.metrics["load"].err != "" {
+				continue
+			}
+			fields := strings.Fields(host.metrics["load"].value)
+			if len(fields) == 3 {
+				if value, ok := metricNumber(fields[i]); ok {
+					m.sample(host.hostname, nil, value)
+				}
+			}
+		}
+	}
+	if local != nil {
+		m.gauge("hladmin_config_drift", "Whether the deployed configuration differs from the local repository HEAD")
+		for _, host := range hosts {
+			if host.err == "" {
+				_, drifted := local.drift(host)
+				m.sample(host.hostname, nil, boolValue(drifted))
+			}
+		}
+	}
+	m.gauge("hladmin_host_maintenance", "Whether the host is in maintenance mode and was not probed")
+	for _, host := range hosts {
+		m.sample(host.hostname, nil, 0)
+	}
+	sort.Strings(skipped)
+	for _, host := range skipped {
+		m.sample(host, nil, 1)
+	}
+	m.gauge("hladmin_probe_cycle_duration_seconds", "Time taken to probe every host")
+	m.sample("", nil, time.Since(start).Seconds())
+	m.gauge("hladmin_probe_timestamp_seconds", "When the last probe finished, as a Unix timestamp")
+	m.sample("", nil, float64(time.Now().Unix()))
+
+	e.mu.Lock()
+	e.metrics = []byte(m.String())
+	e.mu.Unlock()
+	return nil
+}
+
+// ratio returns a percentage metric of a probed host as a fraction
+func (h hostInfo) ratio(column string) (float64, bool) {
+	if h.err != "" || h.metrics[column].err != "" {
+		return 0, false
+	}
+	value, ok := metricNumber(h.metrics[column].value)
+	return value / 100, ok
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsWriter writes metrics in the Prometheus text format
+type metricsWriter struct {
+	strings.Builder
+	name string
+}
+
+// gauge starts a gauge, whose samples follow
+func (m *metricsWriter) gauge(name, help string) {
+	m.name = name
+	fmt.Fprintf(m, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// sample writes a sample of the current gauge. host, unless empty, and
+// labels, given as name and value pairs, become its labels.
+func (m *metricsWriter) sample(host string, labels []string, value float64) {
+	var pairs []string
+	if host != "" {
+		pairs = append(pairs, fmt.Sprintf(`host="%s"`, escapeLabel(host)))
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], escapeLabel(labels[i+1])))
+	}
+	if len(pairs) > 0 {
+		fmt.Fprintf(m, "%s{%s} %g\n", m.name, strings.Join(pairs, ","), value)
+	} else {
+		fmt.Fprintf(m, "%s %g\n", m.name, value)
+	}
+}
+
+// escapeLabel escapes a label value for the Prometheus text format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
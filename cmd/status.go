@@ -1,156 +1,640 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
-	"text/tabwriter"
+	"time"
 
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/config"
 	"github.com/claby2/hladmin/internal/executor"
 	"github.com/spf13/cobra"
 )
 
 var statusCmd = &cobra.Command{
-	Use:           hostUsagePattern("status"),
-	Short:         "Show status information for specified hosts",
-	Long:          hostLongDescription("Display HOSTCLASS, configuration revision, and other useful system information."),
-	RunE:          runStatus,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+	Use:               hostUsagePattern("status"),
+	Short:             "Show status information for specified hosts",
+	Long:              hostLongDescription("Display HOSTCLASS, configuration revision, and other useful system information."),
+	RunE:              runStatus,
+	ValidArgsFunction: completeHosts,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
 }
 
-type hostInfo struct {
-	hostname  string
-	hostclass string
-	version   string
-	repo      string
-	diskUsage string
-	memUsage  string
-}
-
-func getLinuxMemoryCommand() string {
-	return "free | grep '^Mem:' | awk '{printf \"%.0f%%\", $3/$2*100}'"
-}
-
-func getMacOSMemoryCommand() string {
-	return `vm_stat | awk '
-		/^Pages free/ { free = $3 }
-		/^Pages inactive/ { inactive = $3 }
-		/^Pages wired/ { wired = $3 }
-		/^Pages active/ { active = $3 }
-		END {
-			total = free + inactive + wired + active
-			if (total > 0) {
-				used = wired + active
-				printf "%.0f%%", used/total*100
-			} else {
-				print "0%"
-			}
-		}'`
+var statusColumns string
+var statusDrift bool
+var statusWatch time.Duration
+var statusCheck bool
+var statusSort string
+var statusFilters []string
+var statusWhere []string
+var statusFormat string
+var statusCached bool
+var statusMaxAge time.Duration
+
+func init() {
+	statusCmd.Flags().StringVar(&statusColumns, "columns", "", "Comma-separated list of columns to show, or to add to the defaults when starting with +")
+	statusCmd.Flags().BoolVar(&statusDrift, "drift", false, "Compare deployed revisions with the local repository and exit non-zero on drift")
+	statusCmd.Flags().DurationVar(&statusWatch, "watch", 0, "Refresh the table every interval until interrupted (default interval 5s)")
+	statusCmd.Flags().Lookup("watch").NoOptDefVal = "5s"
+	statusCmd.Flags().BoolVar(&statusCheck, "check", false, "Print a one-line summary and exit with a Nagios plugin code (0 OK, 1 warning, 2 critical, 3 unknown)")
+	statusCmd.Flags().StringVar(&statusSort, "sort", "", "Sort hosts by a column, descending with a leading - (e.g. --sort=-disk)")
+	statusCmd.Flags().StringArrayVar(&statusFilters, "filter", nil, "Only show hosts whose column matches, e.g. 'mem>80%' (repeatable)")
+	statusCmd.Flags().StringArrayVar(&statusWhere, "where", nil, "Only probe hosts with a class, group or variable, e.g. class=server (repeatable)")
+	statusCmd.Flags().StringVar(&statusFormat, "format", "", "Print each host with a Go template instead of the table, e.g. '{{.Hostname}} {{.Disk}}'")
+	statusCmd.Flags().BoolVar(&statusCached, "cached", false, "Show cached results without probing hosts")
+	statusCmd.Flags().DurationVar(&statusMaxAge, "max-age", 0, "Show cached results younger than this instead of probing (e.g. 5m)")
+	statusCmd.RegisterFlagCompletionFunc("columns", completeStatusColumns)
+	statusCmd.RegisterFlagCompletionFunc("sort", completeStatusSort)
 }
 
-func getMemoryCommand() string {
-	return fmt.Sprintf("if command -v free >/dev/null 2>&1; then %s; else %s; fi",
-		getLinuxMemoryCommand(), getMacOSMemoryCommand())
+type hostInfo struct {
+	hostname string
+	// metrics holds the probe results by column name
+	metrics map[string]metric
+	// err is the reason the host could not be probed, empty on success
+	err string
+	// duration is how long the probe took
+	duration time.Duration
+	// seen is when metrics were measured, zero if never. Hosts that could
+	// not be probed keep their last cached metrics, which are stale.
+	seen time.Time
 }
 
-func createCompoundStatusCommand() string {
-	memCmd := getMemoryCommand()
-	return fmt.Sprintf(`
-echo -n "$HOSTCLASS|||" && \
-echo -n "$(nixos-version --configuration-revision 2>/dev/null || darwin-version --configuration-revision 2>/dev/null || echo 'unknown')|||" && \
-echo -n "$(nix flake metadata $HOME/nix-config 2>/dev/null | grep "Revision:" | awk '{print $2}')|||" && \
-echo -n "$(df -h / | tail -1 | awk '{print $5}')|||" && \
-echo -n "$(%s)" && \
-echo
-`, memCmd)
+// stale reports whether the host could not be probed and its metrics are
+// the last known ones from the cache
+func (h hostInfo) stale() bool {
+	return h.err != "" && h.metrics != nil
 }
 
-func parseCompoundOutput(hostname, output string) hostInfo {
-	info := hostInfo{hostname: hostname}
+// builtinColumns lists the built-in status columns in display order
+var builtinColumns = []string{"hostclass", "version", "repo", "disk", "mem"}
 
-	// Split by delimiter
-	parts := strings.Split(strings.TrimSpace(output), "|||")
+// optionalColumns lists the built-in columns that are only shown when
+// selected with --columns
+var optionalColumns = []string{"uptime", "load", "cpus", "kernel", "generation", "failed", "reboot"}
 
-	// If we don't get exactly 5 parts, return error values
-	if len(parts) != 5 {
-		info.hostclass = "error"
-		info.version = "error"
-		info.repo = "error"
-		info.diskUsage = "error"
-		info.memUsage = "error"
-		return info
+// value returns the value of the named column, or "-" if it is empty or was
+// not measured, and the reason its probe failed
+func (h hostInfo) value(column string) (string, string) {
+	m, ok := h.metrics[column]
+	if !ok || (m.value == "" && m.err == "") {
+		return "-", ""
 	}
+	return m.value, m.err
+}
 
-	info.hostclass = strings.TrimSpace(parts[0])
-	info.version = strings.TrimSpace(parts[1])
-	info.repo = strings.TrimSpace(parts[2])
-	info.diskUsage = strings.TrimSpace(parts[3])
-	info.memUsage = strings.TrimSpace(parts[4])
+// selectColumns returns the columns to display: the --columns selection, or
+// the default built-in and every user-defined column. A selection starting
+// with "+" adds to the default columns.
+func selectColumns(cfg *config.HostConfig, selection string) ([]string, error) {
+	custom := cfg.ColumnNames()
+	for _, name := range custom {
+		if containsColumn(builtinColumns, name) || containsColumn(optionalColumns, name) {
+			return nil, fmt.Errorf("column '%s' in the config conflicts with a built-in column", name)
+		}
+	}
+	defaults := append(append([]string{}, builtinColumns...), custom...)
+	available := append(append([]string{}, defaults...), optionalColumns...)
+
+	if selection == "" {
+		return defaults, nil
+	}
 
-	return info
+	var columns []string
+	if strings.HasPrefix(selection, "+") {
+		columns = defaults
+		selection = selection[1:]
+	}
+	for _, name := range strings.Split(selection, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" || name == "hostname" {
+			continue
+		}
+		if !containsColumn(available, name) {
+			return nil, fmt.Errorf("unknown column '%s' (available: %s)", name, strings.Join(available, ", "))
+		}
+		if !containsColumn(columns, name) {
+			columns = append(columns, name)
+		}
+	}
+	return columns, nil
 }
 
-func collectHostInfo(hosts []string) ([]hostInfo, error) {
-	command := createCompoundStatusCommand()
+func containsColumn(columns []string, name string) bool {
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
 
-	// Execute compound command on all hosts in parallel using executor with progress
-	results, err := executor.ExecuteOnHostsParallelWithProgress(hosts, command, "Collecting host status")
-	if err != nil {
-		return nil, err
+// collectHostInfo probes hosts for the given columns, which may include
+// probes that are not displayed. A spinner is shown while probing if
+// progress is set; otherwise the probes run within limits.
+func collectHostInfo(cfg *config.HostConfig, hosts []string, columns []string, progress bool, limits executor.Limits) ([]hostInfo, error) {
+	commands := make([]string, len(hosts))
+	for i, host := range hosts {
+		commands[i] = createStatusScript(cfg.Repo(host).Path, columns, cfg.Columns(host))
+	}
+
+	// Execute the probe on all hosts in parallel using executor with progress
+	var results []executor.Result
+	var err error
+	if progress {
+		results, err = executor.ExecuteCommandsParallelWithProgress(hosts, commands, "Collecting host status")
+	} else {
+		results, err = executor.ExecuteCommandsParallelLimited(hosts, commands, limits)
 	}
-	if err = executor.ResultsError(results); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
+	// Every host gets a row; hosts that could not be probed carry the reason
 	var hostInfos []hostInfo
 	for _, result := range results {
 		if result.Err != nil {
-			// Create error hostInfo
-			hostInfos = append(hostInfos, hostInfo{
-				hostname:  result.Hostname,
-				hostclass: "error",
-				version:   "error",
-				repo:      "error",
-				diskUsage: "error",
-				memUsage:  "error",
-			})
-		} else {
-			// Parse the compound output
-			hostInfos = append(hostInfos, parseCompoundOutput(result.Hostname, result.Stdout))
+			hostInfos = append(hostInfos, hostInfo{hostname: result.Hostname, err: failureReason(result), duration: result.Duration})
+			continue
 		}
+		hostInfos = append(hostInfos, hostInfo{
+			hostname: result.Hostname,
+			metrics:  parseProbeOutput(result.Stdout, columns),
+			duration: result.Duration,
+		})
 	}
 
 	return hostInfos, nil
 }
 
+// cachedHostInfo returns the status of a host from its cache entry, seen
+// when the oldest of the shown metrics was probed
+func cachedHostInfo(hostname string, entry config.CachedStatus, seen time.Time) hostInfo {
+	host := hostInfo{hostname: hostname, metrics: make(map[string]metric), seen: seen}
+	for name, m := range entry.Metrics {
+		host.metrics[name] = metric{value: m.Value, err: m.Err}
+	}
+	return host
+}
+
+// cacheEntry returns the cache entry of a host probed at time t
+func cacheEntry(host hostInfo, t time.Time) config.CachedStatus {
+	entry := config.CachedStatus{Time: t, Metrics: make(map[string]config.CachedMetric)}
+	for name, m := range host.metrics {
+		entry.Metrics[name] = config.CachedMetric{Value: m.value, Err: m.err, Time: t}
+	}
+	return entry
+}
+
+// failureReason returns why a command failed on a host: the last line it
+// wrote to stderr, which for ssh failures is the ssh error, or the error
+// itself
+func failureReason(result executor.Result) string {
+	lines := strings.Split(strings.TrimSpace(result.Stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return result.Err.Error()
+}
+
+// statusRequest is what a status invocation shows: the hosts to probe, the
+// hosts in maintenance that are listed without probing, the columns and the
+// probes they need, the local repository when comparing for drift, and how
+// the probed hosts are filtered and sorted
+type statusRequest struct {
+	cfg       *config.HostConfig
+	hostnames []string
+	skipped   []string
+	columns   []string
+	probes    []string
+	local     *localRepo
+	filters   []statusFilter
+	sort      string
+	// checked are metrics with a threshold that are evaluated without
+	// being shown
+	checked []string
+	// cachedOnly shows cached results without probing, and maxAge uses
+	// cached results younger than it. showSeen adds the SEEN column.
+	cachedOnly bool
+	maxAge     time.Duration
+	showSeen   bool
+}
+
+// addProbe probes column on every host without necessarily showing it
+func (r *statusRequest) addProbe(column string) {
+	if column != "hostname" && !containsColumn(r.probes, column) {
+		r.probes = append(r.probes, column)
+	}
+}
+
 func runStatus(cmd *cobra.Command, args []string) error {
-	hostnames, err := resolveHosts(args)
+	err := showStatus(args)
+	var exitErr *ExitError
+	if statusCheck && err != nil && !errors.As(err, &exitErr) {
+		fmt.Printf("%s - %v\n", checkUnknown, err)
+		return &ExitError{Code: int(checkUnknown)}
+	}
+	return err
+}
+
+func showStatus(args []string) error {
+	cfg, hostnames, skipped, err := resolveTargetsSkipping(args)
 	if err != nil {
 		return err
 	}
+	if len(hostnames) == 0 && len(skipped) == 0 {
+		return fmt.Errorf("at least one hostname must be specified")
+	}
+	if statusWatch < 0 {
+		return fmt.Errorf("--watch interval must be positive")
+	}
+	if statusWatch > 0 && statusCheck {
+		return fmt.Errorf("--watch and --check cannot be combined")
+	}
+	if statusFormat != "" && (statusWatch > 0 || statusCheck || statusDrift) {
+		return fmt.Errorf("--format cannot be combined with --watch, --check or --drift")
+	}
+	if statusMaxAge < 0 {
+		return fmt.Errorf("--max-age must be positive")
+	}
+	if statusWatch > 0 && (statusCached || statusMaxAge > 0) {
+		return fmt.Errorf("--watch cannot be combined with --cached or --max-age")
+	}
+	columns, err := selectColumns(cfg, statusColumns)
+	if err != nil {
+		return err
+	}
+	available := append(append(append([]string{}, builtinColumns...), cfg.ColumnNames()...), optionalColumns...)
 
-	// Collect information for all hosts using optimized compound command
-	hosts, err := collectHostInfo(hostnames)
+	// Leave out hosts by their inventory attributes before probing them
+	if len(statusWhere) > 0 {
+		var clauses []whereClause
+		for _, clause := range statusWhere {
+			where, err := parseWhereClause(clause)
+			if err != nil {
+				return err
+			}
+			clauses = append(clauses, where)
+		}
+		hostnames = filterWhere(cfg, hostnames, clauses)
+		skipped = filterWhere(cfg, skipped, clauses)
+		if len(hostnames) == 0 && len(skipped) == 0 {
+			return fmt.Errorf("no hosts match --where %s", strings.Join(statusWhere, " --where "))
+		}
+	}
+
+	req := &statusRequest{
+		cfg:        cfg,
+		hostnames:  hostnames,
+		skipped:    skipped,
+		columns:    columns,
+		probes:     columns,
+		sort:       statusSort,
+		cachedOnly: statusCached,
+		maxAge:     statusMaxAge,
+		showSeen:   statusCached || statusMaxAge > 0,
+	}
+	if statusDrift {
+		if req.local, err = openLocalRepo(cfg); err != nil {
+			return err
+		}
+		for _, probe := range driftProbes {
+			req.addProbe(probe)
+		}
+	}
+	for _, expression := range statusFilters {
+		filter, err := parseStatusFilter(expression, available)
+		if err != nil {
+			return err
+		}
+		req.filters = append(req.filters, filter)
+		req.addProbe(filter.column)
+	}
+	if statusCheck {
+		// Thresholds apply whether or not their column is shown
+		for _, metric := range cfg.ThresholdMetrics() {
+			if containsColumn(available, metric) && !containsColumn(columns, metric) {
+				req.checked = append(req.checked, metric)
+				req.addProbe(metric)
+			}
+		}
+	}
+	if column := strings.TrimPrefix(statusSort, "-"); column != "" {
+		if column != "hostname" && !containsColumn(available, column) {
+			return fmt.Errorf("unknown sort column '%s' (available: hostname, %s)", column, strings.Join(available, ", "))
+		}
+		req.addProbe(column)
+	}
+
+	if statusWatch > 0 {
+		return watchStatus(req, args, statusWatch)
+	}
+
+	if statusFormat != "" {
+		tmpl, err := parseStatusFormat(statusFormat)
+		if err != nil {
+			return err
+		}
+		hosts, err := req.collect(false)
+		if err != nil {
+			return err
+		}
+		return printStatusFormat(os.Stdout, tmpl, hosts)
+	}
+
+	hosts, err := req.collect(!statusCheck)
 	if err != nil {
 		return err
 	}
+	view := req.view(hosts)
+
+	if statusCheck {
+		state, summary := checkSummary(view.alerts, len(hosts))
+		fmt.Println(summary)
+		if state == checkOK {
+			return nil
+		}
+		return &ExitError{Code: int(state)}
+	}
+
+	view.table.render(os.Stdout)
+	view.notes.print(os.Stdout)
+	return view.err(req)
+}
+
+// collect probes the hosts with a single probe per host, then filters and
+// sorts them. Cached results are used instead when requested, and for hosts
+// that cannot be probed. Hosts in maintenance that were left out are listed
+// without querying them.
+func (r *statusRequest) collect(progress bool) ([]hostInfo, error) {
+	cache, err := config.LoadStatusCache()
+	if err != nil {
+		colors.Warning.Fprintf(os.Stderr, "Warning: %v\n", err)
+		cache = make(map[string]config.CachedStatus)
+	}
+
+	now := time.Now()
+	byHost := make(map[string]hostInfo)
+	var toProbe []string
+	for _, hostname := range r.hostnames {
+		seen, cached := cache[hostname].Measured(r.probes)
+		switch {
+		case cached && (r.cachedOnly || now.Sub(seen) <= r.maxAge):
+			byHost[hostname] = cachedHostInfo(hostname, cache[hostname], seen)
+		case r.cachedOnly:
+			byHost[hostname] = hostInfo{hostname: hostname}
+		default:
+			toProbe = append(toProbe, hostname)
+		}
+	}
+
+	if len(toProbe) > 0 {
+		probed, err := collectHostInfo(r.cfg, toProbe, r.probes, progress, executor.Limits{})
+		if err != nil {
+			return nil, err
+		}
+
+		updates := make(map[string]config.CachedStatus)
+		for _, host := range probed {
+			if host.err == "" {
+				host.seen = now
+				updates[host.hostname] = cacheEntry(host, now)
+			} else if seen, ok := cache[host.hostname].Measured(r.probes); ok {
+				stale := cachedHostInfo(host.hostname, cache[host.hostname], seen)
+				host.metrics, host.seen = stale.metrics, stale.seen
+			}
+			byHost[host.hostname] = host
+		}
+		if len(updates) > 0 {
+			if err := config.UpdateStatusCache(updates); err != nil {
+				colors.Warning.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	var hosts []hostInfo
+	for _, hostname := range r.hostnames {
+		hosts = append(hosts, byHost[hostname])
+	}
+	for _, hostname := range r.skipped {
+		hosts = append(hosts, hostInfo{hostname: hostname})
+	}
+
+	if len(r.filters) > 0 {
+		var kept []hostInfo
+		for _, host := range hosts {
+			keep := true
+			for _, filter := range r.filters {
+				keep = keep && filter.matches(host)
+			}
+			if keep {
+				kept = append(kept, host)
+			}
+		}
+		hosts = kept
+	}
+	if r.sort != "" {
+		sortHosts(hosts, r.sort)
+	}
+	return hosts, nil
+}
+
+// statusView is the status table of a set of probed hosts, with the
+// footnotes of its failed cells and the alerts raised by thresholds, failed
+// probes and drift. Row i+1 of the table belongs to host i.
+type statusView struct {
+	table   *table
+	notes   footnotes
+	alerts  []alert
+	shown   int
+	failed  int
+	drifted int
+}
 
-	// Print columnar output
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
-	fmt.Fprintf(w, "HOSTNAME\tHOSTCLASS\tVERSION\tREPO\tDISK\tMEM\n")
+// view lays out the status of hosts, coloring values that reach their
+// thresholds
+func (r *statusRequest) view(hosts []hostInfo) *statusView {
+	v := &statusView{table: &table{}, shown: len(hosts)}
 
+	// Show when values were measured if any are not from this run
+	showSeen := r.showSeen
 	for _, host := range hosts {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-			host.hostname,
-			host.hostclass,
-			host.version,
-			host.repo,
-			host.diskUsage,
-			host.memUsage,
-		)
+		showSeen = showSeen || host.stale()
 	}
 
-	w.Flush()
+	header := []string{"HOSTNAME"}
+	for _, column := range r.columns {
+		header = append(header, strings.ToUpper(column))
+	}
+	if r.local != nil {
+		header = append(header, "DRIFT")
+	}
+	header = append(header, "MAINT")
+	if showSeen {
+		header = append(header, "SEEN")
+	}
+	v.table.addRow(header...)
+
+	now := time.Now()
+	for _, host := range hosts {
+		row := []cell{{text: host.hostname}}
+		seen := cell{text: "never"}
+		if !host.seen.IsZero() {
+			seen.text = formatAge(now.Sub(host.seen))
+		}
+
+		if host.err != "" {
+			v.failed++
+			v.alerts = append(v.alerts, alert{checkCritical, fmt.Sprintf("%s could not be probed: %s", host.hostname, host.err)})
+			note := cell{text: fmt.Sprintf("error[%d]", v.notes.add(host.hostname, "", host.err)), color: colors.Error}
+			if host.stale() {
+				// Show the last known values, dimmed, and the error with
+				// their age
+				for _, column := range r.columns {
+					value, _ := host.value(column)
+					row = append(row, cell{text: value, color: colors.Secondary})
+				}
+				if r.local != nil {
+					row = append(row, cell{text: "-", color: colors.Secondary})
+				}
+				seen = cell{text: seen.text + " (stale) " + note.text, color: colors.Error}
+			} else {
+				for range r.columns {
+					row = append(row, note)
+				}
+				if r.local != nil {
+					row = append(row, note)
+				}
+			}
+		} else {
+			for _, column := range r.columns {
+				row = append(row, v.metricCell(r, host, column))
+			}
+			for _, metric := range r.checked {
+				v.metricCell(r, host, metric)
+			}
+			if r.local != nil {
+				state := cell{text: "-"}
+				if host.metrics != nil {
+					var hasDrifted bool
+					if state.text, hasDrifted = r.local.drift(host); hasDrifted {
+						v.drifted++
+						state.color = colors.Warning
+						v.alerts = append(v.alerts, alert{checkWarning, fmt.Sprintf("%s drift %s", host.hostname, state.text)})
+					}
+				}
+				row = append(row, state)
+			}
+		}
+		row = append(row, cell{text: maintenanceColumn(r.cfg, host.hostname)})
+		if showSeen {
+			row = append(row, seen)
+		}
+		v.table.rows = append(v.table.rows, row)
+	}
+	return v
+}
+
+// metricCell returns the cell of a column of a probed host, raising an alert
+// if the column has a threshold that the value reaches or the value cannot
+// be compared with
+func (v *statusView) metricCell(r *statusRequest, host hostInfo, column string) cell {
+	value, reason := host.value(column)
+	threshold, hasThreshold := r.cfg.Threshold(host.hostname, column)
+	if reason != "" {
+		if hasThreshold {
+			v.alerts = append(v.alerts, alert{checkUnknown, fmt.Sprintf("%s %s: %s", host.hostname, column, reason)})
+		}
+		return cell{text: fmt.Sprintf("error[%d]", v.notes.add(host.hostname, column, reason)), color: colors.Error}
+	}
+	if r.local != nil && (column == "version" || column == "repo") {
+		value = shortRevision(value)
+	}
+	// Hosts in maintenance that were not probed have nothing to compare
+	if !hasThreshold || host.metrics == nil {
+		return cell{text: value}
+	}
+
+	number, ok := metricNumber(value)
+	switch {
+	case !ok:
+		v.alerts = append(v.alerts, alert{checkUnknown, fmt.Sprintf("%s %s '%s' is not a number", host.hostname, column, value)})
+		return cell{text: value}
+	case threshold.Critical(number):
+		v.alerts = append(v.alerts, alert{checkCritical, fmt.Sprintf("%s %s %s (crit %s)", host.hostname, column, value, threshold.FormatLevel(threshold.Crit))})
+		return cell{text: value, color: colors.Error}
+	case threshold.Warning(number):
+		v.alerts = append(v.alerts, alert{checkWarning, fmt.Sprintf("%s %s %s (warn %s)", host.hostname, column, value, threshold.FormatLevel(threshold.Warn))})
+		return cell{text: value, color: colors.Warning}
+	}
+	return cell{text: value}
+}
+
+// err returns the error status exits with: hosts that could not be probed,
+// then hosts that have drifted
+func (v *statusView) err(r *statusRequest) error {
+	if v.failed > 0 {
+		return fmt.Errorf("%d of %d hosts could not be probed", v.failed, v.shown)
+	}
+	if v.drifted > 0 {
+		return fmt.Errorf("%d of %d hosts have drifted from %s (%s)", v.drifted, v.shown, r.local.path, shortRevision(r.local.head))
+	}
 	return nil
 }
+
+// footnotes collects the reasons cells failed. Identical reasons for the same
+// column share a footnote. An empty column stands for every column of a host
+// that could not be probed.
+type footnotes struct {
+	notes []footnote
+}
+
+type footnote struct {
+	column string
+	reason string
+	hosts  []string
+}
+
+// add records that column failed on host for reason and returns the number of
+// its footnote
+func (f *footnotes) add(host, column, reason string) int {
+	for i := range f.notes {
+		if f.notes[i].column == column && f.notes[i].reason == reason {
+			f.notes[i].hosts = append(f.notes[i].hosts, host)
+			return i + 1
+		}
+	}
+	f.notes = append(f.notes, footnote{column: column, reason: reason, hosts: []string{host}})
+	return len(f.notes)
+}
+
+func (f *footnotes) print(w io.Writer) {
+	if len(f.notes) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	for i, note := range f.notes {
+		if note.column == "" {
+			fmt.Fprintf(w, "%s %s: %s\n", colors.Error.Sprintf("[%d]", i+1), strings.Join(note.hosts, ", "), note.reason)
+		} else {
+			fmt.Fprintf(w, "%s %s on %s: %s\n", colors.Error.Sprintf("[%d]", i+1), note.column, strings.Join(note.hosts, ", "), note.reason)
+		}
+	}
+}
+
+// maintenanceColumn returns the MAINT column of host: "-" when it is not in
+// maintenance, otherwise when maintenance ends
+func maintenanceColumn(cfg *config.HostConfig, hostname string) string {
+	entry, ok := cfg.Maintenance[hostname]
+	if !ok {
+		return "-"
+	}
+	if entry.Until.IsZero() {
+		return "yes"
+	}
+	return "until " + entry.Until.Local().Format("2006-01-02 15:04")
+}
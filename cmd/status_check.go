@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// checkState is the state of a Nagios plugin, whose value is its exit code
+type checkState int
+
+const (
+	checkOK checkState = iota
+	checkWarning
+	checkCritical
+	checkUnknown
+)
+
+func (s checkState) String() string {
+	switch s {
+	case checkOK:
+		return "OK"
+	case checkWarning:
+		return "WARNING"
+	case checkCritical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+// severity orders states from OK through UNKNOWN, WARNING and CRITICAL
+func (s checkState) severity() int {
+	return map[checkState]int{checkOK: 0, checkUnknown: 1, checkWarning: 2, checkCritical: 3}[s]
+}
+
+// alert is a problem found by status, such as a metric beyond its threshold
+type alert struct {
+	state checkState
+	text  string
+}
+
+// metricNumber returns the number a metric value starts with, such as 97
+// for "97%" or 0.5 for the load "0.5 0.4 0.3"
+func metricNumber(value string) (float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64)
+	return number, err == nil
+}
+
+// checkSummary returns the overall state of alerts on hosts and a one-line
+// summary in the format of Nagios plugins, listing the worst alerts first
+func checkSummary(alerts []alert, hosts int) (checkState, string) {
+	if len(alerts) == 0 {
+		return checkOK, fmt.Sprintf("OK - %d hosts within thresholds", hosts)
+	}
+
+	state := checkOK
+	counts := make(map[checkState]int)
+	for _, a := range alerts {
+		counts[a.state]++
+		if a.state.severity() > state.severity() {
+			state = a.state
+		}
+	}
+
+	var parts, texts []string
+	for _, s := range []checkState{checkCritical, checkWarning, checkUnknown} {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], strings.ToLower(s.String())))
+		}
+		for _, a := range alerts {
+			if a.state == s {
+				texts = append(texts, a.text)
+			}
+		}
+	}
+	return state, fmt.Sprintf("%s - %s on %d hosts: %s", state, strings.Join(parts, ", "), hosts, strings.Join(texts, "; "))
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/config"
+)
+
+// driftProbes lists the probes needed to compute drift. _repo_dirty is not a
+// column; it prints the first changed file of the host's repository.
+var driftProbes = []string{"version", "repo", "_repo_dirty"}
+
+// shortRevisionLength is the length revisions are shortened to in drift mode
+const shortRevisionLength = 12
+
+// dirtyProbe returns the command reporting uncommitted changes in the
+// repository at repoPath
+func dirtyProbe(repoPath string) string {
+	return fmt.Sprintf(`changes=$(cd %s && git status --porcelain) || exit 1; printf '%%s\n' "$changes" | head -n 1`, repoPath)
+}
+
+// localRepo is the local configuration repository that hosts are compared to
+type localRepo struct {
+	path string
+	head string
+}
+
+// openLocalRepo opens the repository configured for localhost
+func openLocalRepo(cfg *config.HostConfig) (*localRepo, error) {
+	repoPath, err := localRepoPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	repo := &localRepo{path: repoPath}
+
+	head, err := repo.git("rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of local repository %s: %v", repo.path, err)
+	}
+	repo.head = head
+	return repo, nil
+}
+
+func (r *localRepo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// hasCommit reports whether rev is a commit known to the local repository
+func (r *localRepo) hasCommit(rev string) bool {
+	_, err := r.git("cat-file", "-e", rev+"^{commit}")
+	return err == nil
+}
+
+// count returns the number of commits reachable from to but not from from
+func (r *localRepo) count(from, to string) (int, error) {
+	output, err := r.git("rev-list", "--count", from+".."+to)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(output)
+}
+
+// drift describes how the deployment of a host differs from the local HEAD:
+// "in-sync", or a comma-separated list of "dirty", "undeployed", "behind N",
+// "ahead N", "diverged" and "unknown revision". It reports whether the host
+// has drifted.
+func (r *localRepo) drift(info hostInfo) (string, bool) {
+	deployed := info.metrics["version"]
+	checkedOut := info.metrics["repo"]
+
+	var states []string
+	deployedRev := strings.TrimSuffix(deployed.value, "-dirty")
+	if deployed.value != deployedRev || info.metrics["_repo_dirty"].value != "" {
+		states = append(states, "dirty")
+	}
+
+	switch {
+	case deployed.err != "" || deployedRev == "" || deployedRev == "unknown":
+		states = append(states, "undeployed")
+	default:
+		if checkedOut.value != "" && checkedOut.value != deployedRev {
+			states = append(states, "undeployed")
+		}
+		states = append(states, r.compare(deployedRev)...)
+	}
+
+	if len(states) == 0 {
+		return "in-sync", false
+	}
+	return strings.Join(states, ", "), true
+}
+
+// compare describes how rev relates to the local HEAD
+func (r *localRepo) compare(rev string) []string {
+	if rev == r.head {
+		return nil
+	}
+	if !r.hasCommit(rev) {
+		return []string{"unknown revision"}
+	}
+
+	behind, err := r.count(rev, r.head)
+	if err != nil {
+		return []string{"unknown revision"}
+	}
+	ahead, err := r.count(r.head, rev)
+	if err != nil {
+		return []string{"unknown revision"}
+	}
+
+	switch {
+	case behind > 0 && ahead > 0:
+		return []string{"diverged"}
+	case behind > 0:
+		return []string{fmt.Sprintf("behind %d", behind)}
+	case ahead > 0:
+		return []string{fmt.Sprintf("ahead %d", ahead)}
+	}
+	return nil
+}
+
+// shortRevision shortens a full revision, keeping a -dirty suffix
+func shortRevision(rev string) string {
+	base := strings.TrimSuffix(rev, "-dirty")
+	if len(base) <= shortRevisionLength {
+		return rev
+	}
+	return base[:shortRevisionLength] + rev[len(base):]
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// The methods below expose a host's status to --format templates, as in
+// {{.Hostname}} {{.Disk}}. Values that were not measured are "-", failed
+// values are "error", and Err gives the reason a host could not be probed.
+
+func (h hostInfo) Hostname() string   { return h.hostname }
+func (h hostInfo) HostClass() string  { return h.Metric("hostclass") }
+func (h hostInfo) Version() string    { return h.Metric("version") }
+func (h hostInfo) Repo() string       { return h.Metric("repo") }
+func (h hostInfo) Disk() string       { return h.Metric("disk") }
+func (h hostInfo) Mem() string        { return h.Metric("mem") }
+func (h hostInfo) Uptime() string     { return h.Metric("uptime") }
+func (h hostInfo) Load() string       { return h.Metric("load") }
+func (h hostInfo) CPUs() string       { return h.Metric("cpus") }
+func (h hostInfo) Kernel() string     { return h.Metric("kernel") }
+func (h hostInfo) Generation() string { return h.Metric("generation") }
+func (h hostInfo) Failed() string     { return h.Metric("failed") }
+func (h hostInfo) Reboot() string     { return h.Metric("reboot") }
+func (h hostInfo) Err() string        { return h.err }
+
+// Metric returns the value of any column, including user-defined ones, as
+// in {{.Metric "zfs"}}
+func (h hostInfo) Metric(column string) string {
+	if h.err != "" {
+		return "error"
+	}
+	value, reason := h.value(column)
+	if reason != "" {
+		return "error"
+	}
+	return value
+}
+
+// parseStatusFormat parses a --format template. A trailing newline is added
+// so each host is printed on its own line.
+func parseStatusFormat(format string) (*template.Template, error) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// printStatusFormat renders the template once for each host. Like the
+// table, it fails when any host could not be probed.
+func printStatusFormat(w io.Writer, tmpl *template.Template, hosts []hostInfo) error {
+	failed := 0
+	for _, host := range hosts {
+		if err := tmpl.Execute(w, host); err != nil {
+			return fmt.Errorf("failed to render format for %s: %v", host.hostname, err)
+		}
+		if host.err != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hosts could not be probed", failed, len(hosts))
+	}
+	return nil
+}
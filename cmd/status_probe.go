@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/config"
+)
+
+// The status probe is a POSIX shell script run on each host in a single
+// round-trip. It runs one command per metric and prints one record per
+// metric:
+//
+//	<name> TAB ok TAB <first line of stdout>
+//	<name> TAB err TAB <stderr, or the exit status>
+//
+// Tabs and newlines in values are replaced by spaces, so a failing or
+// misbehaving command only affects its own record.
+const probePreamble = `_hladmin_err=$(mktemp 2>/dev/null || echo "/tmp/hladmin-probe.$$")
+trap 'rm -f "$_hladmin_err"' EXIT
+probe() {
+	_hladmin_out=$(sh -c "$2" 2>"$_hladmin_err")
+	_hladmin_status=$?
+	if [ "$_hladmin_status" -eq 0 ]; then
+		printf '%s\tok\t%s\n' "$1" "$(printf '%s\n' "$_hladmin_out" | head -n 1 | tr '\t' ' ')"
+	else
+		_hladmin_msg=$(tr '\t\n' '  ' <"$_hladmin_err")
+		[ -n "$_hladmin_msg" ] || _hladmin_msg="exit status $_hladmin_status"
+		printf '%s\terr\t%s\n' "$1" "$_hladmin_msg"
+	fi
+}
+`
+
+// metric is the result of a single probe
+type metric struct {
+	value string
+	// err is the reason the probe failed, empty on success
+	err string
+}
+
+func getLinuxMemoryCommand() string {
+	return "free | grep '^Mem:' | awk '{printf \"%.0f%%\", $3/$2*100}'"
+}
+
+func getMacOSMemoryCommand() string {
+	return `vm_stat | awk '
+		/^Pages free/ { free = $3 }
+		/^Pages inactive/ { inactive = $3 }
+		/^Pages wired/ { wired = $3 }
+		/^Pages active/ { active = $3 }
+		END {
+			total = free + inactive + wired + active
+			if (total > 0) {
+				used = wired + active
+				printf "%.0f%%", used/total*100
+			} else {
+				print "0%"
+			}
+		}'`
+}
+
+// osCommand returns a command running linux on Linux and darwin on macOS.
+// Other systems fail the probe.
+func osCommand(linux, darwin string) string {
+	return fmt.Sprintf(`case "$(uname -s)" in
+Linux) %s ;;
+Darwin) %s ;;
+*) echo "unsupported OS: $(uname -s)" >&2; exit 1 ;;
+esac`, linux, darwin)
+}
+
+func getMemoryCommand() string {
+	return osCommand(getLinuxMemoryCommand(), getMacOSMemoryCommand())
+}
+
+// formatUptime is an awk program formatting a number of seconds as "3d 4h",
+// "4h 12m" or "12m"
+const formatUptime = `awk '{
+	d = int($1 / 86400); h = int($1 % 86400 / 3600); m = int($1 % 3600 / 60)
+	if (d > 0) printf "%dd %dh\n", d, h
+	else if (h > 0) printf "%dh %dm\n", h, m
+	else printf "%dm\n", m
+}'`
+
+func getUptimeCommand() string {
+	return osCommand(
+		"awk '{ print $1 }' /proc/uptime | "+formatUptime,
+		`boot=$(sysctl -n kern.boottime | sed 's/^{ sec = \([0-9]*\).*/\1/') || exit 1; echo $(($(date +%s) - boot)) | `+formatUptime,
+	)
+}
+
+func getLoadCommand() string {
+	return osCommand(
+		"cut -d ' ' -f 1-3 /proc/loadavg",
+		"sysctl -n vm.loadavg | awk '{ print $2, $3, $4 }'",
+	)
+}
+
+func getCPUCountCommand() string {
+	return osCommand("nproc", "sysctl -n hw.ncpu")
+}
+
+// getGenerationCommand prints the number and date of the current system
+// generation. NixOS and nix-darwin both keep generations as
+// system-<n>-link in the system profile, created when they were activated.
+func getGenerationCommand() string {
+	return fmt.Sprintf(`link=$(readlink /nix/var/nix/profiles/system) || { echo "no system profile" >&2; exit 1; }
+generation=${link#system-}
+generation=${generation%%-link}
+date=$(%s) || exit 1
+echo "$generation ($date)"`, osCommand(
+		`stat -c %y "/nix/var/nix/profiles/$link" | cut -d ' ' -f 1`,
+		`stat -f %Sm -t %Y-%m-%d "/nix/var/nix/profiles/$link"`,
+	))
+}
+
+// getFailedUnitsCommand counts failed systemd units, or launchd jobs whose
+// last exit status was non-zero
+func getFailedUnitsCommand() string {
+	return osCommand(
+		`units=$(systemctl list-units --state=failed --no-legend --plain) || exit 1; printf '%s' "$units" | grep -c . || true`,
+		`jobs=$(launchctl list) || exit 1; printf '%s\n' "$jobs" | awk 'NR > 1 && $2 != "0" && $2 != "-" { n++ } END { print n + 0 }'`,
+	)
+}
+
+// getRebootCommand prints whether the booted system differs from the
+// current one in its kernel, initrd or kernel modules. nix-darwin does not
+// manage the kernel, so macOS never needs a reboot for it.
+func getRebootCommand() string {
+	return osCommand(
+		`[ -e /run/booted-system ] || { echo "no /run/booted-system" >&2; exit 1; }
+for path in kernel initrd kernel-modules; do
+	if [ "$(readlink /run/booted-system/$path)" != "$(readlink /run/current-system/$path)" ]; then
+		echo yes; exit 0
+	fi
+done; echo no`,
+		"echo no",
+	)
+}
+
+// builtinProbe returns the command measuring a built-in column
+func builtinProbe(column, repoPath string) string {
+	switch column {
+	case "hostclass":
+		return `printf '%s\n' "$HOSTCLASS"`
+	case "version":
+		return "nixos-version --configuration-revision 2>/dev/null || darwin-version --configuration-revision"
+	case "repo":
+		return fmt.Sprintf(`metadata=$(nix flake metadata %s) || exit 1; printf '%%s\n' "$metadata" | awk '/Revision:/ { print $2 }'`, repoPath)
+	case "disk":
+		return "df -P / | awk 'NR == 2 { print $5 }'"
+	case "mem":
+		return getMemoryCommand()
+	case "uptime":
+		return getUptimeCommand()
+	case "load":
+		return getLoadCommand()
+	case "cpus":
+		return getCPUCountCommand()
+	case "kernel":
+		return "uname -r"
+	case "generation":
+		return getGenerationCommand()
+	case "failed":
+		return getFailedUnitsCommand()
+	case "reboot":
+		return getRebootCommand()
+	case "_repo_dirty":
+		return dirtyProbe(repoPath)
+	}
+	return ""
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// columnCommand returns the command measuring a user-defined column for a
+// host, given the definitions that apply to it. The last definition matching
+// the host's OS wins; without one the column is empty.
+func columnCommand(definitions []config.StatusColumn) string {
+	var arms []string
+	for i := len(definitions) - 1; i >= 0; i-- {
+		definition := definitions[i]
+		pattern := "*"
+		if definition.OS != "" {
+			pattern = strings.ToLower(definition.OS)
+		}
+		arms = append(arms, fmt.Sprintf("%s) %s ;;", pattern, definition.Command))
+		if definition.OS == "" {
+			break
+		}
+	}
+	return fmt.Sprintf("case \"$(uname -s | tr '[:upper:]' '[:lower:]')\" in\n%s\nesac", strings.Join(arms, "\n"))
+}
+
+// createStatusScript returns the probe script measuring columns on a host
+// whose repository is at repoPath, given the user-defined column
+// definitions that apply to it
+func createStatusScript(repoPath string, columns []string, definitions []config.StatusColumn) string {
+	var script strings.Builder
+	script.WriteString(probePreamble)
+
+	for _, column := range columns {
+		command := builtinProbe(column, repoPath)
+		if command == "" {
+			var applicable []config.StatusColumn
+			for _, definition := range definitions {
+				if definition.Name == column {
+					applicable = append(applicable, definition)
+				}
+			}
+			if len(applicable) == 0 {
+				command = "true"
+			} else {
+				command = columnCommand(applicable)
+			}
+		}
+		fmt.Fprintf(&script, "probe %s %s\n", column, shellQuote(command))
+	}
+
+	// Run under sh regardless of the login shell on the host
+	return "sh -c " + shellQuote(script.String())
+}
+
+// parseProbeOutput parses the records printed by the probe script. Columns
+// without a record are reported as failed.
+func parseProbeOutput(output string, columns []string) map[string]metric {
+	metrics := make(map[string]metric, len(columns))
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		value := strings.TrimSpace(fields[2])
+		switch fields[1] {
+		case "ok":
+			metrics[fields[0]] = metric{value: value}
+		case "err":
+			metrics[fields[0]] = metric{err: value}
+		}
+	}
+
+	for _, column := range columns {
+		if _, ok := metrics[column]; !ok {
+			metrics[column] = metric{err: "no result from probe"}
+		}
+	}
+	return metrics
+}
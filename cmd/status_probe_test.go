@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProbeOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		columns []string
+		want    map[string]metric
+	}{
+		{
+			name:    "values",
+			output:  "disk\tok\t17%\nmem\tok\t46%\n",
+			columns: []string{"disk", "mem"},
+			want:    map[string]metric{"disk": {value: "17%"}, "mem": {value: "46%"}},
+		},
+		{
+			name:    "failed probe",
+			output:  "version\terr\tsh: nixos-version: not found\ndisk\tok\t17%\n",
+			columns: []string{"version", "disk"},
+			want:    map[string]metric{"version": {err: "sh: nixos-version: not found"}, "disk": {value: "17%"}},
+		},
+		{
+			name:    "missing record",
+			output:  "disk\tok\t17%\n",
+			columns: []string{"disk", "mem"},
+			want:    map[string]metric{"disk": {value: "17%"}, "mem": {err: "no result from probe"}},
+		},
+		{
+			name:    "value with tabs and padding",
+			output:  "load\tok\t 0.51\t0.36 \n",
+			columns: []string{"load"},
+			want:    map[string]metric{"load": {value: "0.51\t0.36"}},
+		},
+		{
+			name:    "empty value",
+			output:  "tailscale\tok\t\n",
+			columns: []string{"tailscale"},
+			want:    map[string]metric{"tailscale": {}},
+		},
+		{
+			name:    "noise from login scripts",
+			output:  "Welcome to altaria\ndisk\tbogus\t17%\nmem\tok\t46%\n",
+			columns: []string{"disk", "mem"},
+			want:    map[string]metric{"disk": {err: "no result from probe"}, "mem": {value: "46%"}},
+		},
+		{
+			name:    "probes that are not columns",
+			output:  "_repo_dirty\tok\t1\n",
+			columns: nil,
+			want:    map[string]metric{"_repo_dirty": {value: "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseProbeOutput(tt.output, tt.columns)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProbeOutput(%q) = %+v, want %+v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
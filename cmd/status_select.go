@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/claby2/hladmin/internal/config"
+)
+
+// filterExpression matches a --filter expression such as mem>80%
+var filterExpression = regexp.MustCompile(`^([a-z][a-z0-9_-]*)\s*(>=|<=|!=|=|>|<)\s*(.*)$`)
+
+// statusFilter keeps hosts whose column compares to value with op
+type statusFilter struct {
+	column string
+	op     string
+	value  string
+}
+
+// parseStatusFilter parses a --filter expression. Columns are checked
+// against available.
+func parseStatusFilter(expression string, available []string) (statusFilter, error) {
+	match := filterExpression.FindStringSubmatch(strings.TrimSpace(expression))
+	if match == nil {
+		return statusFilter{}, fmt.Errorf("invalid filter '%s': expected <column><op><value> with one of >, >=, <, <=, =, !=", expression)
+	}
+	filter := statusFilter{column: match[1], op: match[2], value: strings.TrimSpace(match[3])}
+	if filter.column != "hostname" && !containsColumn(available, filter.column) {
+		return filter, fmt.Errorf("unknown column '%s' in filter '%s'", filter.column, expression)
+	}
+	if filter.op != "=" && filter.op != "!=" {
+		if _, ok := metricNumber(filter.value); !ok {
+			return filter, fmt.Errorf("filter '%s' compares with %s and needs a number", expression, filter.op)
+		}
+	}
+	return filter, nil
+}
+
+// matches reports whether host passes the filter. Hosts that could not be
+// probed and failed values never match.
+func (f statusFilter) matches(host hostInfo) bool {
+	if host.err != "" || host.metrics == nil {
+		return false
+	}
+	value, reason := host.hostname, ""
+	if f.column != "hostname" {
+		value, reason = host.value(f.column)
+	}
+	if reason != "" {
+		return false
+	}
+
+	number, isNumber := metricNumber(value)
+	want, wantNumber := metricNumber(f.value)
+	switch f.op {
+	case "=":
+		return value == f.value || (isNumber && wantNumber && number == want)
+	case "!=":
+		return value != f.value && !(isNumber && wantNumber && number == want)
+	}
+	if !isNumber {
+		return false
+	}
+	switch f.op {
+	case ">":
+		return number > want
+	case ">=":
+		return number >= want
+	case "<":
+		return number < want
+	}
+	return number <= want
+}
+
+// whereClause keeps hosts by an inventory attribute before they are probed:
+// their cached class, a group containing them, or a variable
+type whereClause struct {
+	key    string
+	value  string
+	negate bool
+}
+
+// parseWhereClause parses a --where clause such as class=server or
+// group!=desktops
+func parseWhereClause(clause string) (whereClause, error) {
+	key, value, found := strings.Cut(clause, "=")
+	if !found || key == "" {
+		return whereClause{}, fmt.Errorf("invalid --where '%s': expected <attribute>=<value> or <attribute>!=<value>", clause)
+	}
+	where := whereClause{key: strings.TrimSpace(key), value: strings.TrimSpace(value)}
+	if strings.HasSuffix(where.key, "!") {
+		where.key = strings.TrimSuffix(where.key, "!")
+		where.negate = true
+	}
+	return where, nil
+}
+
+// matches reports whether host has the attribute
+func (w whereClause) matches(cfg *config.HostConfig, host string) bool {
+	var matched bool
+	switch w.key {
+	case "class":
+		matched = cfg.Classes[host] == w.value
+	case "group":
+		matched = containsColumn(cfg.GroupsOf(host), w.value)
+	default:
+		value, ok := cfg.Vars(host)[w.key]
+		matched = ok && value == w.value
+	}
+	return matched != w.negate
+}
+
+// filterWhere returns the hosts matching every clause
+func filterWhere(cfg *config.HostConfig, hosts []string, clauses []whereClause) []string {
+	var kept []string
+	for _, host := range hosts {
+		keep := true
+		for _, clause := range clauses {
+			keep = keep && clause.matches(cfg, host)
+		}
+		if keep {
+			kept = append(kept, host)
+		}
+	}
+	return kept
+}
+
+// sortHosts sorts hosts by a column, numerically when both values are
+// numbers. A leading - sorts in descending order. Hosts without a value
+// for the column are placed last.
+func sortHosts(hosts []hostInfo, column string) {
+	descending := strings.HasPrefix(column, "-")
+	column = strings.TrimPrefix(column, "-")
+
+	key := func(host hostInfo) (string, bool) {
+		if column == "hostname" {
+			return host.hostname, true
+		}
+		if host.err != "" || host.metrics == nil {
+			return "", false
+		}
+		value, reason := host.value(column)
+		return value, reason == "" && value != "-"
+	}
+
+	sort.SliceStable(hosts, func(i, j int) bool {
+		a, aOK := key(hosts[i])
+		b, bOK := key(hosts[j])
+		if !aOK || !bOK {
+			return aOK && !bOK
+		}
+		aNumber, aIsNumber := metricNumber(a)
+		bNumber, bIsNumber := metricNumber(b)
+		if aIsNumber && bIsNumber && aNumber != bNumber {
+			return (aNumber < bNumber) != descending
+		}
+		if a == b {
+			return false
+		}
+		return (a < b) != descending
+	})
+}
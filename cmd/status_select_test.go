@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatusFilterMatches(t *testing.T) {
+	altaria := hostInfo{hostname: "altaria", metrics: map[string]metric{
+		"disk":    {value: "17%"},
+		"mem":     {value: "46%"},
+		"load":    {value: "0.51 0.36 0.24"},
+		"failed":  {value: "0"},
+		"reboot":  {value: "yes"},
+		"version": {err: "sh: nixos-version: not found"},
+		"zfs":     {},
+	}}
+	unreachable := hostInfo{hostname: "onix", err: "ssh: connect to host onix port 22: No route to host"}
+
+	tests := []struct {
+		name   string
+		filter statusFilter
+		host   hostInfo
+		want   bool
+	}{
+		{name: "greater", filter: statusFilter{"mem", ">", "40%"}, host: altaria, want: true},
+		{name: "not greater", filter: statusFilter{"disk", ">", "17"}, host: altaria, want: false},
+		{name: "greater or equal", filter: statusFilter{"disk", ">=", "17"}, host: altaria, want: true},
+		{name: "less", filter: statusFilter{"disk", "<", "20%"}, host: altaria, want: true},
+		{name: "less or equal", filter: statusFilter{"mem", "<=", "45"}, host: altaria, want: false},
+		{name: "first field of load", filter: statusFilter{"load", ">", "0.5"}, host: altaria, want: true},
+		{name: "numeric equality", filter: statusFilter{"failed", "=", "0.0"}, host: altaria, want: true},
+		{name: "numeric inequality", filter: statusFilter{"failed", "!=", "0"}, host: altaria, want: false},
+		{name: "string equality", filter: statusFilter{"reboot", "=", "yes"}, host: altaria, want: true},
+		{name: "string inequality", filter: statusFilter{"reboot", "!=", "no"}, host: altaria, want: true},
+		{name: "hostname", filter: statusFilter{"hostname", "=", "altaria"}, host: altaria, want: true},
+		{name: "string compared as number", filter: statusFilter{"reboot", ">", "1"}, host: altaria, want: false},
+		{name: "failed value", filter: statusFilter{"version", "!=", "x"}, host: altaria, want: false},
+		{name: "empty value", filter: statusFilter{"zfs", "=", "-"}, host: altaria, want: true},
+		{name: "unreachable host", filter: statusFilter{"disk", "!=", "0"}, host: unreachable, want: false},
+		{name: "unreachable hostname", filter: statusFilter{"hostname", "=", "onix"}, host: unreachable, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(tt.host); got != tt.want {
+				t.Errorf("%+v.matches(%s) = %v, want %v", tt.filter, tt.host.hostname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortHosts(t *testing.T) {
+	host := func(hostname, disk string) hostInfo {
+		return hostInfo{hostname: hostname, metrics: map[string]metric{"disk": {value: disk}}}
+	}
+	hosts := []hostInfo{
+		host("charizard", "9%"),
+		{hostname: "onix", err: "unreachable"},
+		host("altaria", "61%"),
+		{hostname: "bulbasaur", metrics: map[string]metric{"disk": {err: "df: not found"}}},
+		host("dragonite", "100%"),
+		host("eevee", "9%"),
+		{hostname: "flareon", metrics: map[string]metric{}},
+	}
+
+	tests := []struct {
+		name   string
+		column string
+		want   []string
+	}{
+		{name: "hostname", column: "hostname", want: []string{"altaria", "bulbasaur", "charizard", "dragonite", "eevee", "flareon", "onix"}},
+		{name: "hostname descending", column: "-hostname", want: []string{"onix", "flareon", "eevee", "dragonite", "charizard", "bulbasaur", "altaria"}},
+		{name: "numeric", column: "disk", want: []string{"charizard", "eevee", "altaria", "dragonite", "onix", "bulbasaur", "flareon"}},
+		{name: "numeric descending", column: "-disk", want: []string{"dragonite", "altaria", "charizard", "eevee", "onix", "bulbasaur", "flareon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := append([]hostInfo{}, hosts...)
+			sortHosts(sorted, tt.column)
+			var got []string
+			for _, host := range sorted {
+				got = append(got, host.hostname)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortHosts(%s) = %v, want %v", tt.column, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/claby2/hladmin/internal/colors"
+	"github.com/claby2/hladmin/internal/executor"
+)
+
+// watchStatus redraws the status table every interval until interrupted.
+// Cells that changed since the previous refresh are highlighted, and the
+// SEEN column gives the time since each host was last probed successfully.
+func watchStatus(req *statusRequest, args []string, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Keep ssh connections open between refreshes. The masters exit on their
+	// own shortly after the last refresh.
+	controlDir, err := os.MkdirTemp("", "hladmin-ssh-")
+	if err != nil {
+		return fmt.Errorf("failed to create ssh control directory: %v", err)
+	}
+	defer os.RemoveAll(controlDir)
+	executor.ShareConnections(controlDir, 2*interval+10*time.Second)
+
+	req.showSeen = true
+	var previous map[string][]string
+	for {
+		hosts, err := req.collect(false)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		now := time.Now()
+		view := req.view(hosts)
+		current := make(map[string][]string)
+		for i, host := range hosts {
+			row := view.table.rows[i+1]
+			texts := make([]string, len(row))
+			for j := range row {
+				texts[j] = row[j].text
+				if old, ok := previous[host.hostname]; ok && j < len(old) && old[j] != texts[j] {
+					row[j].color = colors.Highlight
+				}
+			}
+			current[host.hostname] = texts
+		}
+		previous = current
+
+		var screen bytes.Buffer
+		fmt.Fprintf(&screen, "Every %s: hladmin status %s  %s\n\n", interval, strings.Join(args, " "), now.Format("15:04:05"))
+		view.table.render(&screen)
+		view.notes.print(&screen)
+
+		// Redraw in place, clearing what is left of each line and below the
+		// table instead of the whole screen to avoid flicker
+		fmt.Print("\033[H" + strings.ReplaceAll(screen.String(), "\n", "\033[K\n") + "\033[J")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// formatAge formats a duration as its largest whole unit, such as "12s",
+// "3m" or "2h"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// cell is a table cell, optionally printed in a color
+type cell struct {
+	text  string
+	color *color.Color
+}
+
+// table lays out rows in aligned columns like text/tabwriter, but measures
+// cells by their text so colored cells stay aligned
+type table struct {
+	rows [][]cell
+}
+
+// addRow appends a row of uncolored cells
+func (t *table) addRow(texts ...string) {
+	row := make([]cell, len(texts))
+	for i, text := range texts {
+		row[i] = cell{text: text}
+	}
+	t.rows = append(t.rows, row)
+}
+
+// render writes the table with two spaces between columns
+func (t *table) render(w io.Writer) {
+	var widths []int
+	for _, row := range t.rows {
+		for i, c := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(c.text); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for _, row := range t.rows {
+		var line strings.Builder
+		for i, c := range row {
+			text := c.text
+			if c.color != nil {
+				text = c.color.Sprint(text)
+			}
+			line.WriteString(text)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c.text)+2))
+			}
+		}
+		line.WriteString("\n")
+		io.WriteString(w, line.String())
+	}
+}
@@ -18,6 +18,7 @@ var (
 	Header    = color.New(color.FgCyan, color.Bold)
 	Hostname  = color.New(color.FgYellow, color.Bold)
 	Secondary = color.New(color.FgHiBlack)
+	Highlight = color.New(color.ReverseVideo)
 )
 
 func init() {
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// allGroup is the conventional name of the group expected to contain every host
+const allGroup = "all"
+
+// Check loads the host configuration and returns it together with every
+// problem found: syntax errors, duplicate group definitions and members,
+// unknown or cyclic group references, groups that nothing references and
+// hosts missing from @all. The configuration is returned even when it has
+// problems so that callers can inspect it further.
+func Check() (*HostConfig, []Problem, error) {
+	config, problems, err := load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	problems = append(problems, config.lint...)
+	problems = append(problems, config.checkReferences()...)
+	problems = append(problems, config.checkAllGroup()...)
+
+	SortProblems(problems)
+	return config, problems, nil
+}
+
+// SortProblems orders problems by file and line
+func SortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+}
+
+// HostLocation returns the file and line where host was first listed, either
+// in a group or by the inventory source that discovered it
+func (c *HostConfig) HostLocation(host string) (string, int) {
+	pos := c.hostPos[host]
+	return pos.file, pos.line
+}
+
+// definedGroups returns the names of groups defined in the config file,
+// ordered by where they were defined
+func (c *HostConfig) definedGroups() []string {
+	names := make([]string, 0, len(c.groupPos))
+	for name := range c.groupPos {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := c.groupPos[names[i]], c.groupPos[names[j]]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		return a.line < b.line
+	})
+	return names
+}
+
+// checkReferences reports @group members and directive targets that name
+// unknown groups, groups that are part of a reference cycle, and groups that
+// are neither the default group, @all, a directive target nor a member of
+// another group
+func (c *HostConfig) checkReferences() []Problem {
+	var problems []Problem
+	referenced := map[string]bool{c.DefaultGroup: true, allGroup: true}
+
+	for _, name := range c.definedGroups() {
+		pos := c.groupPos[name]
+		for _, member := range c.Groups[name] {
+			if !strings.HasPrefix(member, "@") || strings.HasPrefix(member, "@"+classPrefix) {
+				continue
+			}
+			referenced[member[1:]] = true
+			if _, exists := c.Groups[member[1:]]; !exists {
+				problems = append(problems, pos.problem("group '%s' references unknown group '%s'", name, member[1:]))
+			}
+		}
+
+		if c.reaches(name, name, make(map[string]bool)) {
+			problems = append(problems, pos.problem("group '%s' is part of a reference cycle", name))
+		}
+	}
+
+	for _, rule := range c.rules {
+		if !rule.isGroup() || strings.HasPrefix(rule.target, "@"+classPrefix) {
+			continue
+		}
+		referenced[rule.target[1:]] = true
+		if _, exists := c.Groups[rule.target[1:]]; !exists {
+			problems = append(problems, rule.pos.problem("directive references unknown group '%s'", rule.target[1:]))
+		}
+	}
+
+	for _, name := range c.definedGroups() {
+		if !referenced[name] {
+			problems = append(problems, c.groupPos[name].problem("group '%s' is not referenced by the default group or any other group", name))
+		}
+	}
+	return problems
+}
+
+// reaches reports whether target can be reached by following @group members
+// starting from group
+func (c *HostConfig) reaches(group, target string, visited map[string]bool) bool {
+	for _, member := range c.Groups[group] {
+		if !strings.HasPrefix(member, "@") {
+			continue
+		}
+		next := member[1:]
+		if next == target {
+			return true
+		}
+		if visited[next] {
+			continue
+		}
+		visited[next] = true
+		if c.reaches(next, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAllGroup reports hosts that belong to a group but are missing from the
+// @all group, if one is defined
+func (c *HostConfig) checkAllGroup() []Problem {
+	if _, exists := c.Groups[allGroup]; !exists {
+		return nil
+	}
+
+	allHosts, err := c.ExpandSelector("@" + allGroup)
+	if err != nil {
+		// Reported by checkReferences
+		return nil
+	}
+	inAll := make(map[string]bool, len(allHosts))
+	for _, host := range allHosts {
+		inAll[host] = true
+	}
+
+	var problems []Problem
+	for _, host := range c.AllHosts() {
+		if inAll[host] || !c.grouped(host) {
+			continue
+		}
+		problems = append(problems, c.hostPos[host].problem("host '%s' is missing from @%s", host, allGroup))
+	}
+	return problems
+}
+
+// grouped reports whether host is listed directly in any group
+func (c *HostConfig) grouped(host string) bool {
+	for _, members := range c.Groups {
+		for _, member := range members {
+			if member == host {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// classPrefix marks a selector that resolves hosts by their cached HOSTCLASS
+const classPrefix = "class:"
+
+// GetClassCachePath returns the full path to the cached host to HOSTCLASS map
+func GetClassCachePath() string {
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "classes.json")
+}
+
+// LoadClasses loads the cached host to HOSTCLASS map. A missing cache file
+// yields an empty map.
+func LoadClasses() (map[string]string, error) {
+	classes := make(map[string]string)
+
+	cachePath := GetClassCachePath()
+	if cachePath == "" {
+		return classes, nil
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return classes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read class cache %s: %v", cachePath, err)
+	}
+
+	if err := json.Unmarshal(data, &classes); err != nil {
+		return nil, fmt.Errorf("failed to parse class cache %s: %v", cachePath, err)
+	}
+	return classes, nil
+}
+
+// SaveClasses writes the host to HOSTCLASS map to the class cache
+func SaveClasses(classes map[string]string) error {
+	cachePath := GetClassCachePath()
+	if cachePath == "" {
+		return fmt.Errorf("cannot determine cache directory: neither XDG_CACHE_HOME nor HOME is set")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(classes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode class cache: %v", err)
+	}
+
+	if err := os.WriteFile(cachePath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write class cache %s: %v", cachePath, err)
+	}
+	return nil
+}
+
+// hostsWithClass returns the hosts whose cached HOSTCLASS matches class,
+// sorted by hostname
+func (c *HostConfig) hostsWithClass(class string) ([]string, error) {
+	if class == "" {
+		return nil, fmt.Errorf("empty class name: @%s", classPrefix)
+	}
+
+	var hosts []string
+	for host, hostClass := range c.Classes {
+		if hostClass == class {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("unknown class: %s (run 'hladmin inventory refresh' to update the class cache)", class)
+	}
+
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+// ClassConflicts reports hosts whose group membership contradicts their cached
+// HOSTCLASS. A group whose name matches a known class is expected to contain
+// exactly the hosts reporting that class.
+func (c *HostConfig) ClassConflicts() []string {
+	knownClasses := make(map[string]bool)
+	for _, class := range c.Classes {
+		knownClasses[class] = true
+	}
+
+	groupNames := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		if knownClasses[name] {
+			groupNames = append(groupNames, name)
+		}
+	}
+	sort.Strings(groupNames)
+
+	var conflicts []string
+	for _, group := range groupNames {
+		members := make(map[string]bool)
+		groupHosts, _ := c.ExpandSelector("@" + group)
+		for _, host := range groupHosts {
+			members[host] = true
+			if class, known := c.Classes[host]; known && class != group {
+				conflicts = append(conflicts, fmt.Sprintf("%s is in group @%s but reports HOSTCLASS %s", host, group, class))
+			}
+		}
+
+		hosts, _ := c.hostsWithClass(group)
+		for _, host := range hosts {
+			if !members[host] {
+				conflicts = append(conflicts, fmt.Sprintf("%s reports HOSTCLASS %s but is not in group @%s", host, group, group))
+			}
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// columnName matches valid status column names
+var columnName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// StatusColumn is a status column defined by a column directive
+type StatusColumn struct {
+	Name string
+	// Command is the shell command whose output is shown in the column
+	Command string
+	// OS limits the column to hosts whose uname -s matches, compared
+	// case-insensitively. Empty means every OS.
+	OS string
+
+	// scope limits the column to a host or group when set
+	scope *hostRule
+}
+
+// parseColumn parses the arguments of a column directive: a name, optional
+// @group and os=<name> scopes, and the command
+func (c *HostConfig) parseColumn(fields []string, pos position) (StatusColumn, error) {
+	column := StatusColumn{Name: fields[0]}
+	if !columnName.MatchString(column.Name) {
+		return column, fmt.Errorf("invalid column name '%s': use lowercase letters, digits, - and _", column.Name)
+	}
+
+	rest := fields[1:]
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest[0], "@") && column.scope == nil:
+			rule := c.addRule(rest[0], pos)
+			column.scope = &rule
+		case strings.HasPrefix(rest[0], "os=") && column.OS == "":
+			column.OS = strings.TrimPrefix(rest[0], "os=")
+		default:
+			column.Command = strings.Join(rest, " ")
+			return column, nil
+		}
+		rest = rest[1:]
+	}
+	return column, fmt.Errorf("column '%s' requires a command", column.Name)
+}
+
+// ColumnNames returns the names of the user-defined status columns in the
+// order they are first defined
+func (c *HostConfig) ColumnNames() []string {
+	var names []string
+	for _, column := range c.columns {
+		names = appendUnique(names, column.Name)
+	}
+	return names
+}
+
+// Columns returns the column definitions that apply to host, in the order
+// they appear in the config. A column may have several definitions, for
+// example one per OS; the last one matching the host's OS is used.
+func (c *HostConfig) Columns(host string) []StatusColumn {
+	var columns []StatusColumn
+	for _, column := range c.columns {
+		if column.scope == nil || column.scope.appliesTo(c, host) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
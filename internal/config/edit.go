@@ -0,0 +1,401 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Editor modifies the config files one directive at a time, leaving comments,
+// blank lines and the order of directives untouched. Groups are edited in the
+// file that defines them, which may be an included file or a fragment; new
+// directives go to the main config file.
+type Editor struct {
+	files []*editFile
+}
+
+// editFile holds the lines of a single config file being edited
+type editFile struct {
+	path    string
+	lines   []string
+	mode    os.FileMode
+	changed bool
+}
+
+// line is a reference to a single line of a file being edited
+type line struct {
+	file  *editFile
+	index int
+}
+
+// OpenEditor reads the config file and every file it includes for editing. A
+// missing main config file is treated as empty and is created on Save.
+func OpenEditor() (*Editor, error) {
+	configPath := GetConfigPath()
+	if configPath == "" {
+		return nil, fmt.Errorf("cannot determine config directory: neither XDG_CONFIG_HOME nor HOME is set")
+	}
+
+	// Parse the files only to find out which ones are included
+	config := newHostConfig(nil)
+	if _, err := config.parseConfigFiles(configPath); err != nil {
+		return nil, err
+	}
+	paths := appendUnique([]string{configPath}, config.Files()...)
+
+	editor := &Editor{}
+	for _, path := range paths {
+		file, err := readEditFile(path)
+		if err != nil {
+			return nil, err
+		}
+		editor.files = append(editor.files, file)
+	}
+	return editor, nil
+}
+
+func readEditFile(path string) (*editFile, error) {
+	file := &editFile{path: path, mode: 0o644}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+	if stat, err := os.Stat(path); err == nil {
+		file.mode = stat.Mode().Perm()
+	}
+
+	file.lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	return file, nil
+}
+
+// Path returns the path of the main config file
+func (e *Editor) Path() string {
+	return e.main().path
+}
+
+func (e *Editor) main() *editFile {
+	return e.files[0]
+}
+
+// directive returns the fields of the line, or nil for blank lines and comments
+func (l line) directive() []string {
+	text := strings.TrimSpace(l.file.lines[l.index])
+	if text == "" || strings.HasPrefix(text, "#") {
+		return nil
+	}
+	return strings.Fields(text)
+}
+
+// set replaces the line with fields, keeping its indentation
+func (l line) set(fields []string) {
+	text := l.file.lines[l.index]
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	l.file.lines[l.index] = indent + strings.Join(fields, " ")
+	l.file.changed = true
+}
+
+// directives returns every line across all files that starts with keyword
+func (e *Editor) directives(keyword string) []line {
+	var lines []line
+	for _, file := range e.files {
+		for i := range file.lines {
+			l := line{file: file, index: i}
+			if fields := l.directive(); len(fields) > 0 && fields[0] == keyword {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
+
+// groupLines returns the lines defining group
+func (e *Editor) groupLines(group string) []line {
+	var lines []line
+	for _, l := range e.directives("group") {
+		if fields := l.directive(); len(fields) >= 2 && fields[1] == group {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// lastDirective returns the index of the last line of file starting with
+// keyword, or -1 if there is none
+func (f *editFile) lastDirective(keyword string) int {
+	last := -1
+	for i := range f.lines {
+		if fields := (line{file: f, index: i}).directive(); len(fields) > 0 && fields[0] == keyword {
+			last = i
+		}
+	}
+	return last
+}
+
+// insertAfter inserts text after index i, or appends it when i is -1
+func (f *editFile) insertAfter(i int, text string) {
+	if i < 0 {
+		f.lines = append(f.lines, text)
+	} else {
+		f.lines = append(f.lines[:i+1], append([]string{text}, f.lines[i+1:]...)...)
+	}
+	f.changed = true
+}
+
+// AddHosts adds hosts to the last definition of group, skipping hosts that
+// are already members. It returns the hosts that were added.
+func (e *Editor) AddHosts(group string, hosts ...string) ([]string, error) {
+	lines := e.groupLines(group)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("group '%s' is not defined in %s or its included files", group, e.Path())
+	}
+
+	l := lines[len(lines)-1]
+	fields := l.directive()
+	existing := len(fields)
+	fields = appendUnique(fields, hosts...)
+	if len(fields) > existing {
+		l.set(fields)
+	}
+	return fields[existing:], nil
+}
+
+// RemoveHost removes host from every group. Groups left without members are
+// deleted, unless another directive refers to them, in which case nothing is
+// changed and an error names the references. It returns the names of the
+// groups that were changed.
+func (e *Editor) RemoveHost(host string) ([]string, error) {
+	// Work out the new members of every definition before changing anything
+	remaining := make(map[line][]string)
+	emptied := make(map[string]bool)
+	kept := make(map[string]bool)
+	var changed []string
+	for _, l := range e.directives("group") {
+		fields := l.directive()
+		if len(fields) < 3 {
+			continue
+		}
+		members := make([]string, 0, len(fields)-2)
+		for _, member := range fields[2:] {
+			if member != host {
+				members = append(members, member)
+			}
+		}
+		if len(members) == 0 {
+			emptied[fields[1]] = true
+		} else {
+			kept[fields[1]] = true
+		}
+		if len(members) < len(fields)-2 {
+			remaining[l] = members
+			changed = appendUnique(changed, fields[1])
+		}
+	}
+
+	for _, group := range changed {
+		if !emptied[group] || kept[group] {
+			continue
+		}
+		if refs := e.groupReferences(group); len(refs) > 0 {
+			return nil, fmt.Errorf("removing %s would leave group '%s' empty, but it is referenced by %s", host, group, strings.Join(refs, ", "))
+		}
+	}
+
+	for _, file := range e.files {
+		lines := make([]string, 0, len(file.lines))
+		for i := range file.lines {
+			l := line{file: file, index: i}
+			members, ok := remaining[l]
+			if !ok {
+				lines = append(lines, file.lines[i])
+				continue
+			}
+			file.changed = true
+			if len(members) > 0 {
+				l.set(append(l.directive()[:2], members...))
+				lines = append(lines, file.lines[i])
+			}
+		}
+		file.lines = lines
+	}
+	return changed, nil
+}
+
+// groupReferences returns the locations of the directives other than its own
+// definitions that refer to group: the default directive, @group members of
+// other groups and directives targeting @group
+func (e *Editor) groupReferences(group string) []string {
+	var refs []string
+	for _, file := range e.files {
+		for i := range file.lines {
+			fields := (line{file: file, index: i}).directive()
+			if len(fields) < 2 {
+				continue
+			}
+			referenced := false
+			switch fields[0] {
+			case "default":
+				referenced = fields[1] == group
+			case "group":
+				referenced = fields[1] != group && containsString(fields[2:], "@"+group)
+			default:
+				i := targetField(fields)
+				referenced = i > 0 && fields[i] == "@"+group
+			}
+			if referenced {
+				refs = append(refs, fmt.Sprintf("%s:%d (%s)", file.path, i+1, fields[0]))
+			}
+		}
+	}
+	return refs
+}
+
+// targetField returns the index of the field holding the host or @group a
+// directive applies to, or -1 for directives without a target
+func targetField(fields []string) int {
+	switch fields[0] {
+	case "var", "repo", "rebuild", "remote", "host":
+		return 1
+	case "column", "threshold":
+		if len(fields) > 2 {
+			return 2
+		}
+	}
+	return -1
+}
+
+// AddGroup defines a new group after the last group definition in the main
+// config file
+func (e *Editor) AddGroup(group string, members ...string) error {
+	if len(e.groupLines(group)) > 0 {
+		return fmt.Errorf("group '%s' is already defined", group)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("group '%s' requires at least one member", group)
+	}
+
+	main := e.main()
+	main.insertAfter(main.lastDirective("group"), strings.Join(append([]string{"group", group}, members...), " "))
+	return nil
+}
+
+// SetDefault sets the default group, replacing the last default directive or
+// adding one to the main config file
+func (e *Editor) SetDefault(group string) {
+	if lines := e.directives("default"); len(lines) > 0 {
+		lines[len(lines)-1].set([]string{"default", group})
+		return
+	}
+	main := e.main()
+	main.insertAfter(main.lastDirective("group"), "default "+group)
+}
+
+// RenameGroup renames a group, updating its definitions, @group references in
+// other groups, directives targeting @group and the default directive
+func (e *Editor) RenameGroup(oldName, newName string) error {
+	if len(e.groupLines(oldName)) == 0 {
+		return fmt.Errorf("group '%s' is not defined in %s or its included files", oldName, e.Path())
+	}
+	if len(e.groupLines(newName)) > 0 {
+		return fmt.Errorf("group '%s' is already defined", newName)
+	}
+
+	for _, l := range e.directives("group") {
+		fields := l.directive()
+		if len(fields) < 2 {
+			continue
+		}
+		renamed := false
+		if fields[1] == oldName {
+			fields[1] = newName
+			renamed = true
+		}
+		for j := 2; j < len(fields); j++ {
+			if fields[j] == "@"+oldName {
+				fields[j] = "@" + newName
+				renamed = true
+			}
+		}
+		if renamed {
+			l.set(fields)
+		}
+	}
+
+	for _, l := range e.directives("default") {
+		if fields := l.directive(); len(fields) == 2 && fields[1] == oldName {
+			l.set([]string{"default", newName})
+		}
+	}
+
+	for _, file := range e.files {
+		for i := range file.lines {
+			l := line{file: file, index: i}
+			fields := l.directive()
+			if len(fields) < 2 {
+				continue
+			}
+			if j := targetField(fields); j > 0 && fields[j] == "@"+oldName {
+				fields[j] = "@" + newName
+				l.set(fields)
+			}
+		}
+	}
+	return nil
+}
+
+// Save writes every changed file atomically, keeping the previous version of
+// each as a .bak file next to it
+func (e *Editor) Save() error {
+	for _, file := range e.files {
+		if file.changed {
+			if err := file.save(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *editFile) save() error {
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	if data, err := os.ReadFile(f.path); err == nil {
+		if err := os.WriteFile(f.path+".bak", data, f.mode); err != nil {
+			return fmt.Errorf("failed to write backup of %s: %v", f.path, err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(f.path)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	content := strings.Join(f.lines, "\n") + "\n"
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file %s: %v", f.path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file %s: %v", f.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file %s: %v", f.path, err)
+	}
+	if err := os.Chmod(tmp.Name(), f.mode); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %v", f.path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace config file %s: %v", f.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testEditor returns an editor for a single in-memory config file
+func testEditor(config string) *Editor {
+	return &Editor{files: []*editFile{{path: "hosts", lines: strings.Split(strings.TrimSuffix(config, "\n"), "\n")}}}
+}
+
+func TestRenameGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		old, new string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:   "definition, members and default",
+			config: "group servers s1 s2\ngroup all @servers d1\ndefault servers\n",
+			old:    "servers", new: "srv",
+			want: "group srv s1 s2\ngroup all @srv d1\ndefault srv\n",
+		},
+		{
+			name: "directive targets",
+			config: `group servers s1
+var @servers backup_dir=/srv
+host @servers user=admin protected
+repo @servers /etc/nixos
+rebuild @servers sudo nixos-rebuild switch
+remote @servers origin main
+column zfs @servers zpool list -H -o health
+threshold disk @servers 80 90
+`,
+			old: "servers", new: "srv",
+			want: `group srv s1
+var @srv backup_dir=/srv
+host @srv user=admin protected
+repo @srv /etc/nixos
+rebuild @srv sudo nixos-rebuild switch
+remote @srv origin main
+column zfs @srv zpool list -H -o health
+threshold disk @srv 80 90
+`,
+		},
+		{
+			name:   "other groups and hosts untouched",
+			config: "group servers s1\ngroup servers2 s2\nvar servers a=b\nthreshold disk 80 90\n# group servers\n",
+			old:    "servers", new: "srv",
+			want: "group srv s1\ngroup servers2 s2\nvar servers a=b\nthreshold disk 80 90\n# group servers\n",
+		},
+		{
+			name:   "unknown group",
+			config: "group servers s1\n",
+			old:    "desktops", new: "workstations",
+			wantErr: true,
+		},
+		{
+			name:   "new name taken",
+			config: "group servers s1\ngroup srv s2\n",
+			old:    "servers", new: "srv",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := testEditor(tt.config)
+			err := editor.RenameGroup(tt.old, tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenameGroup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(editor.main().lines, "\n") + "\n"; !tt.wantErr && got != tt.want {
+				t.Errorf("RenameGroup() config = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveHost(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		host        string
+		want        string
+		wantChanged []string
+		wantErr     string
+	}{
+		{
+			name:        "every group",
+			config:      "group servers s1 s2\ngroup all s1 d1\n",
+			host:        "s1",
+			want:        "group servers s2\ngroup all d1\n",
+			wantChanged: []string{"servers", "all"},
+		},
+		{
+			name:        "unreferenced group is deleted",
+			config:      "group servers s1 s2\ngroup lone s1\n",
+			host:        "s1",
+			want:        "group servers s2\n",
+			wantChanged: []string{"servers", "lone"},
+		},
+		{
+			name:        "group with another definition is kept",
+			config:      "group lone s1\ngroup lone s2\ndefault lone\n",
+			host:        "s1",
+			want:        "group lone s2\ndefault lone\n",
+			wantChanged: []string{"lone"},
+		},
+		{
+			name:   "unknown host",
+			config: "group servers s1\n",
+			host:   "s9",
+			want:   "group servers s1\n",
+		},
+		{
+			name:    "default group",
+			config:  "group lone s1\ndefault lone\n",
+			host:    "s1",
+			wantErr: "hosts:2 (default)",
+		},
+		{
+			name:    "member of another group",
+			config:  "group lone s1\ngroup all @lone\n",
+			host:    "s1",
+			wantErr: "hosts:2 (group)",
+		},
+		{
+			name:    "directive targets",
+			config:  "group lone s1\nhost @lone protected\nthreshold disk @lone 80 90\n",
+			host:    "s1",
+			wantErr: "hosts:2 (host), hosts:3 (threshold)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := testEditor(tt.config)
+			changed, err := editor.RemoveHost(tt.host)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+					t.Fatalf("RemoveHost() error = %v, want references %q", err, tt.wantErr)
+				}
+				if got := strings.Join(editor.main().lines, "\n") + "\n"; got != tt.config {
+					t.Errorf("RemoveHost() changed the config to %q after failing", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RemoveHost() error = %v", err)
+			}
+			if !reflect.DeepEqual(changed, tt.wantChanged) {
+				t.Errorf("RemoveHost() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := strings.Join(editor.main().lines, "\n") + "\n"; got != tt.want {
+				t.Errorf("RemoveHost() config = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
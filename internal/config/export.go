@@ -0,0 +1,308 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportFormats lists the formats accepted by Export
+var ExportFormats = []string{"ansible-ini", "ansible-yaml", "ssh-config", "json", "hosts"}
+
+// ansibleAllGroup is the implicit Ansible group containing every host. A
+// hladmin group of the same name cannot be exported as a separate group.
+const ansibleAllGroup = "all"
+
+// Export writes the inventory in the given format. Hosts carry their resolved
+// variables, and groups keep nested @group members where the format allows it.
+func (c *HostConfig) Export(w io.Writer, format string) error {
+	switch format {
+	case "ansible-ini":
+		return c.exportAnsibleINI(w)
+	case "ansible-yaml":
+		return c.exportAnsibleYAML(w)
+	case "ssh-config":
+		return c.exportSSHConfig(w)
+	case "json":
+		return c.exportJSON(w)
+	case "hosts":
+		return c.exportHostsFile(w)
+	default:
+		return fmt.Errorf("unknown export format '%s' (expected one of: %s)", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+// ansibleGroup is a group split into direct hosts and child groups
+type ansibleGroup struct {
+	name     string
+	hosts    []string
+	children []string
+}
+
+// ansibleGroups returns the groups to export to Ansible. @class:<name>
+// members are expanded to the hosts that currently have the class.
+func (c *HostConfig) ansibleGroups() []ansibleGroup {
+	var groups []ansibleGroup
+	for _, name := range c.GroupNames() {
+		if name == ansibleAllGroup {
+			continue
+		}
+		group := ansibleGroup{name: name}
+		for _, member := range c.Groups[name] {
+			switch {
+			case strings.HasPrefix(member, "@"+classPrefix):
+				hosts, _ := c.hostsWithClass(strings.TrimPrefix(member, "@"+classPrefix))
+				group.hosts = appendUnique(group.hosts, hosts...)
+			case strings.HasPrefix(member, "@"):
+				if child := strings.TrimPrefix(member, "@"); child != ansibleAllGroup {
+					group.children = appendUnique(group.children, child)
+				}
+			default:
+				group.hosts = appendUnique(group.hosts, member)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// ansibleVars returns the variables of host for Ansible sorted by key. The
+// host's connection settings are included as ansible_* variables unless a
+// variable of the same name is set.
+func (c *HostConfig) ansibleVars(host string) [][2]string {
+	vars := make(map[string]string)
+	settings := c.HostSettings(host)
+	if settings.Address != "" {
+		vars["ansible_host"] = settings.Address
+	}
+	if settings.User != "" {
+		vars["ansible_user"] = settings.User
+	}
+	if settings.Port != 0 {
+		vars["ansible_port"] = strconv.Itoa(settings.Port)
+	}
+	if settings.Identity != "" {
+		vars["ansible_ssh_private_key_file"] = settings.Identity
+	}
+	var commonArgs []string
+	if settings.ProxyJump != "" {
+		commonArgs = append(commonArgs, "-o ProxyJump="+settings.ProxyJump)
+	}
+	for _, option := range settings.Options {
+		commonArgs = append(commonArgs, "-o "+option)
+	}
+	if len(commonArgs) > 0 {
+		vars["ansible_ssh_common_args"] = strings.Join(commonArgs, " ")
+	}
+	for key, value := range c.Vars(host) {
+		vars[key] = value
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([][2]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, [2]string{key, vars[key]})
+	}
+	return pairs
+}
+
+// exportAnsibleINI lists every host with its variables under [all], followed by
+// one section per group and a :children section for nested groups
+func (c *HostConfig) exportAnsibleINI(w io.Writer) error {
+	fmt.Fprintln(w, "[all]")
+	for _, host := range c.AllHosts() {
+		line := host
+		for _, pair := range c.ansibleVars(host) {
+			line += " " + pair[0] + "=" + iniQuote(pair[1])
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	for _, group := range c.ansibleGroups() {
+		if len(group.hosts) > 0 || len(group.children) == 0 {
+			fmt.Fprintf(w, "\n[%s]\n", group.name)
+			for _, host := range group.hosts {
+				fmt.Fprintln(w, host)
+			}
+		}
+		if len(group.children) > 0 {
+			fmt.Fprintf(w, "\n[%s:children]\n", group.name)
+			for _, child := range group.children {
+				fmt.Fprintln(w, child)
+			}
+		}
+	}
+	return nil
+}
+
+// iniQuote quotes an Ansible INI value if it contains whitespace or quotes
+func iniQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"'#;") {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// exportAnsibleYAML writes the YAML inventory format, with host variables under
+// all.hosts and the groups as children of all
+func (c *HostConfig) exportAnsibleYAML(w io.Writer) error {
+	fmt.Fprintln(w, "all:")
+
+	hosts := c.AllHosts()
+	if len(hosts) > 0 {
+		fmt.Fprintln(w, "  hosts:")
+		for _, host := range hosts {
+			fmt.Fprintf(w, "    %s:\n", yamlQuote(host))
+			for _, pair := range c.ansibleVars(host) {
+				fmt.Fprintf(w, "      %s: %s\n", yamlQuote(pair[0]), strconv.Quote(pair[1]))
+			}
+		}
+	}
+
+	groups := c.ansibleGroups()
+	if len(groups) > 0 {
+		fmt.Fprintln(w, "  children:")
+		for _, group := range groups {
+			if len(group.hosts) == 0 && len(group.children) == 0 {
+				fmt.Fprintf(w, "    %s: {}\n", yamlQuote(group.name))
+				continue
+			}
+			fmt.Fprintf(w, "    %s:\n", yamlQuote(group.name))
+			if len(group.hosts) > 0 {
+				fmt.Fprintln(w, "      hosts:")
+				for _, host := range group.hosts {
+					fmt.Fprintf(w, "        %s:\n", yamlQuote(host))
+				}
+			}
+			if len(group.children) > 0 {
+				fmt.Fprintln(w, "      children:")
+				for _, child := range group.children {
+					fmt.Fprintf(w, "        %s:\n", yamlQuote(child))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// yamlQuote quotes a YAML mapping key unless it is a plain identifier-like
+// string
+func yamlQuote(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.", r)) {
+			return strconv.Quote(key)
+		}
+	}
+	return key
+}
+
+// exportSSHConfig writes a Host block with the connection settings of every
+// host, tagged with its groups in the form read back by "source ssh_config"
+func (c *HostConfig) exportSSHConfig(w io.Writer) error {
+	for i, host := range c.AllHosts() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Host %s\n", host)
+		settings := c.HostSettings(host)
+		for _, option := range [][2]string{
+			{"HostName", settings.Address},
+			{"User", settings.User},
+			{"Port", portString(settings.Port)},
+			{"IdentityFile", settings.Identity},
+			{"ProxyJump", settings.ProxyJump},
+		} {
+			if option[1] != "" {
+				fmt.Fprintf(w, "    %s %s\n", option[0], option[1])
+			}
+		}
+		for _, option := range settings.Options {
+			name, value, _ := strings.Cut(option, "=")
+			fmt.Fprintf(w, "    %s %s\n", name, value)
+		}
+		if groups := c.GroupsOf(host); len(groups) > 0 {
+			fmt.Fprintf(w, "    # %s %s\n", sshConfigTag, strings.Join(groups, " "))
+		}
+	}
+	return nil
+}
+
+// portString formats a port setting, which is empty when unset
+func portString(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+// exportJSON writes the inventory in the format printed by script sources,
+// with every group expanded to its hosts
+func (c *HostConfig) exportJSON(w io.Writer) error {
+	inv := inventory{
+		Hosts:  c.AllHosts(),
+		Groups: make(map[string][]string),
+		Vars:   make(map[string]map[string]string),
+	}
+	if inv.Hosts == nil {
+		inv.Hosts = []string{}
+	}
+
+	for _, name := range c.GroupNames() {
+		hosts, err := c.ExpandSelector("@" + name)
+		if err != nil {
+			return err
+		}
+		if hosts == nil {
+			hosts = []string{}
+		}
+		inv.Groups[name] = hosts
+	}
+	for _, host := range inv.Hosts {
+		if vars := c.Vars(host); len(vars) > 0 {
+			inv.Vars[host] = vars
+		}
+	}
+
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// exportHostsFile writes /etc/hosts entries with the current address of every
+// host, looking up its configured address if it has one. Hosts that do not
+// resolve are written as comments.
+func (c *HostConfig) exportHostsFile(w io.Writer) error {
+	for _, host := range c.AllHosts() {
+		address := host
+		if settings := c.HostSettings(host); settings.Address != "" {
+			address = settings.Address
+		}
+		addrs, err := net.LookupHost(address)
+		if err != nil || len(addrs) == 0 {
+			fmt.Fprintf(w, "# %s: does not resolve\n", host)
+			continue
+		}
+
+		line := addrs[0] + "\t" + host
+		if groups := c.GroupsOf(host); len(groups) > 0 {
+			line += "\t# " + strings.Join(groups, " ")
+		}
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
@@ -5,13 +5,47 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // HostConfig represents the parsed host configuration
 type HostConfig struct {
 	Groups       map[string][]string
 	DefaultGroup string
+	// Classes maps hostnames to the HOSTCLASS they last reported
+	Classes map[string]string
+	// Hosts lists hosts discovered by inventory sources, in discovery order
+	Hosts []string
+	// Warnings collects non-fatal problems encountered while loading sources
+	Warnings []string
+	// Maintenance maps hostnames in maintenance mode to their maintenance
+	// record
+	Maintenance map[string]Maintenance
+	// IncludeMaintenance keeps hosts in maintenance when groups are expanded
+	IncludeMaintenance bool
+
+	sources    []source
+	cacheTTL   time.Duration
+	groupOrder []string
+	files      []string
+	rules      []hostRule
+	vars       []varRule
+	repos      []repoRule
+	// hostSettings holds host directives in file order
+	hostSettings     []hostSettingsRule
+	confirmThreshold int
+	columns          []StatusColumn
+	thresholds       []Threshold
+	sourceVars       map[string]map[string]string
+
+	// Locations recorded while parsing, used to report problems
+	groupPos   map[string]position
+	hostPos    map[string]position
+	defaultPos position
+	lint       []Problem
 }
 
 // getConfigDir returns the XDG-compliant config directory
@@ -26,8 +60,143 @@ func getConfigDir() string {
 	return filepath.Join(home, ".config", "hladmin")
 }
 
-// GetConfigPath returns the full path to the hosts config file
+// getCacheDir returns the XDG-compliant cache directory
+func getCacheDir() string {
+	if xdgCache := os.Getenv("XDG_CACHE_HOME"); xdgCache != "" {
+		return filepath.Join(xdgCache, "hladmin")
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".cache", "hladmin")
+}
+
+// getStateDir returns the XDG-compliant state directory
+func getStateDir() string {
+	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
+		return filepath.Join(xdgState, "hladmin")
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "hladmin")
+}
+
+// configPathOverride and inventoryName select the config file instead of the
+// default hosts file. They are set from command line flags.
+var (
+	configPathOverride string
+	inventoryName      string
+)
+
+// SetConfigPath makes GetConfigPath return path, taking precedence over
+// HLADMIN_CONFIG and named inventories. An empty path removes the override.
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// SetInventory selects a named inventory from the inventories directory,
+// taking precedence over HLADMIN_CONFIG and HLADMIN_INVENTORY. An empty name
+// removes the selection.
+func SetInventory(name string) {
+	inventoryName = name
+}
+
+// ActiveInventory returns the name of the selected inventory, or an empty
+// string when the default hosts file or an explicit config path is used
+func ActiveInventory() string {
+	if getConfigOverride() != "" {
+		return ""
+	}
+	if inventoryName != "" {
+		return inventoryName
+	}
+	return os.Getenv("HLADMIN_INVENTORY")
+}
+
+// CheckInventory returns an error if the selected inventory, from
+// SetInventory or HLADMIN_INVENTORY, is not a plain file name inside the
+// inventories directory
+func CheckInventory() error {
+	name := ActiveInventory()
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) || name == "." || name == ".." {
+		if inventoryName == "" {
+			return fmt.Errorf("invalid inventory name in HLADMIN_INVENTORY: %s", name)
+		}
+		return fmt.Errorf("invalid inventory name: %s", name)
+	}
+	return nil
+}
+
+// getConfigOverride returns the explicitly selected config path, if any.
+// HLADMIN_CONFIG is ignored when an inventory was selected with SetInventory.
+func getConfigOverride() string {
+	if configPathOverride != "" {
+		return configPathOverride
+	}
+	if inventoryName != "" {
+		return ""
+	}
+	return os.Getenv("HLADMIN_CONFIG")
+}
+
+// GetInventoriesDir returns the directory holding named inventories
+func GetInventoriesDir() string {
+	configDir := getConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "inventories")
+}
+
+// ListInventories returns the names of the inventories in the inventories
+// directory
+func ListInventories() ([]string, error) {
+	inventoriesDir := GetInventoriesDir()
+	if inventoriesDir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(inventoriesDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inventories: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") && !strings.HasSuffix(entry.Name(), ".bak") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// GetConfigPath returns the full path to the hosts config file. An explicit
+// path from SetConfigPath or HLADMIN_CONFIG is used as is; a named inventory
+// from SetInventory or HLADMIN_INVENTORY selects inventories/<name> in the
+// config directory.
 func GetConfigPath() string {
+	if override := getConfigOverride(); override != "" {
+		path, err := filepath.Abs(resolvePath(override, ""))
+		if err != nil {
+			return override
+		}
+		return path
+	}
+
+	if name := ActiveInventory(); name != "" {
+		inventoriesDir := GetInventoriesDir()
+		if inventoriesDir == "" {
+			return ""
+		}
+		return filepath.Join(inventoriesDir, name)
+	}
+
 	configDir := getConfigDir()
 	if configDir == "" {
 		return ""
@@ -35,33 +204,180 @@ func GetConfigPath() string {
 	return filepath.Join(configDir, "hosts")
 }
 
+// Problem describes an issue found at a specific location in the config file
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// Error formats the problem as file:line: message
+func (p Problem) Error() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// position identifies a line in a config file
+type position struct {
+	file string
+	line int
+}
+
+// problem creates a Problem located at p
+func (p position) problem(format string, args ...interface{}) Problem {
+	return Problem{File: p.file, Line: p.line, Message: fmt.Sprintf(format, args...)}
+}
+
+// newHostConfig returns an empty configuration
+func newHostConfig(classes map[string]string) *HostConfig {
+	return &HostConfig{
+		Groups:   make(map[string][]string),
+		Classes:  classes,
+		cacheTTL: defaultCacheTTL,
+
+		confirmThreshold: defaultConfirmThreshold,
+
+		Maintenance:        make(map[string]Maintenance),
+		IncludeMaintenance: includeMaintenance,
+		groupPos:           make(map[string]position),
+		hostPos:            make(map[string]position),
+		sourceVars:         make(map[string]map[string]string),
+	}
+}
+
+// GetFragmentsPattern returns the glob matching config fragments that are
+// loaded after the main config file
+func GetFragmentsPattern() string {
+	configPath := GetConfigPath()
+	if configPath == "" {
+		return ""
+	}
+	return filepath.Join(configPath+".d", "*.conf")
+}
+
 // LoadConfig loads the host configuration from the config file
 func LoadConfig() (*HostConfig, error) {
+	config, problems, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, problems[0]
+	}
+	return config, nil
+}
+
+// load parses the config file and its inventory sources. Unlike LoadConfig it
+// keeps going after a problem so that every problem can be reported.
+func load() (*HostConfig, []Problem, error) {
+	classes, err := LoadClasses()
+	if err != nil {
+		return nil, nil, err
+	}
+	config := newHostConfig(classes)
+	if config.Maintenance, err = LoadMaintenance(); err != nil {
+		return nil, nil, err
+	}
+
 	configPath := GetConfigPath()
 	if configPath == "" {
-		return &HostConfig{Groups: make(map[string][]string)}, nil
+		return config, nil, nil
 	}
 
+	// An explicitly selected config must exist, unlike the default hosts file
+	if getConfigOverride() != "" || ActiveInventory() != "" {
+		if _, err := os.Stat(configPath); err != nil {
+			if name := ActiveInventory(); name != "" {
+				return nil, nil, fmt.Errorf("inventory '%s' not found: %v", name, err)
+			}
+			return nil, nil, fmt.Errorf("config file not found: %v", err)
+		}
+	}
+
+	problems, err := config.parseConfigFiles(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Merge hosts and groups from dynamic inventory sources
+	for _, src := range config.sources {
+		inv, warning, err := src.load(config.cacheTTL)
+		if err != nil {
+			problems = append(problems, src.pos.problem("failed to load %s source: %v", src.kind, err))
+			continue
+		}
+		if warning != "" {
+			config.Warnings = append(config.Warnings, warning)
+		}
+		config.merge(inv, src.pos)
+	}
+
+	// Validate that default group exists if specified
+	if config.DefaultGroup != "" {
+		if _, exists := config.Groups[config.DefaultGroup]; !exists {
+			problems = append(problems, config.defaultPos.problem("default group '%s' is not defined", config.DefaultGroup))
+		}
+	}
+
+	return config, problems, nil
+}
+
+// parseConfigFiles parses the main config file, if it exists, followed by the
+// fragments in its .d directory in lexical order
+func (c *HostConfig) parseConfigFiles(configPath string) ([]Problem, error) {
+	var problems []Problem
+
 	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return &HostConfig{Groups: make(map[string][]string)}, nil
+	if _, err := os.Stat(configPath); err == nil {
+		fileProblems, err := c.parseFile(configPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
 	}
 
-	file, err := os.Open(configPath)
+	fragments, err := filepath.Glob(GetFragmentsPattern())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open config file %s: %v", configPath, err)
+		return nil, fmt.Errorf("failed to list config fragments: %v", err)
 	}
-	defer file.Close()
+	for _, fragment := range fragments {
+		// Fragments may already have been read through an include directive
+		if containsString(c.files, fragment) {
+			continue
+		}
+		fileProblems, err := c.parseFile(fragment, nil)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
+	}
+
+	return problems, nil
+}
 
-	config := &HostConfig{
-		Groups: make(map[string][]string),
+// parseFile reads directives from the config file at path. Syntax errors are
+// returned as problems; findings that do not prevent loading, such as
+// duplicate definitions, are recorded for Check. Stack holds the files that
+// are currently being parsed and is used to detect include cycles.
+func (c *HostConfig) parseFile(path string, stack []string) ([]Problem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file %s: %v", path, err)
 	}
+	defer file.Close()
+
+	c.files = appendUnique(c.files, path)
+	stack = append(stack, path)
 
+	var problems []Problem
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
 		line := strings.TrimSpace(scanner.Text())
+		pos := position{file: path, line: lineNum}
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -70,73 +386,280 @@ func LoadConfig() (*HostConfig, error) {
 
 		fields := strings.Fields(line)
 		if len(fields) < 2 {
-			return nil, fmt.Errorf("invalid syntax on line %d: %s", lineNum, line)
+			problems = append(problems, pos.problem("invalid syntax: %s", line))
+			continue
 		}
 
 		switch fields[0] {
 		case "group":
 			if len(fields) < 3 {
-				return nil, fmt.Errorf("group directive requires at least one host on line %d: %s", lineNum, line)
+				problems = append(problems, pos.problem("group directive requires at least one host: %s", line))
+				continue
 			}
-			groupName := fields[1]
-			hosts := fields[2:]
-			config.Groups[groupName] = hosts
+			c.addGroup(fields[1], fields[2:], pos)
 
 		case "default":
 			if len(fields) != 2 {
-				return nil, fmt.Errorf("default directive requires exactly one group name on line %d: %s", lineNum, line)
+				problems = append(problems, pos.problem("default directive requires exactly one group name: %s", line))
+				continue
 			}
-			config.DefaultGroup = fields[1]
+			c.DefaultGroup = fields[1]
+			c.defaultPos = pos
+
+		case "include":
+			if len(fields) != 2 {
+				problems = append(problems, pos.problem("include directive requires exactly one path or glob: %s", line))
+				continue
+			}
+			problems = append(problems, c.parseInclude(fields[1], pos, stack)...)
+
+		case "var":
+			if len(fields) < 3 {
+				problems = append(problems, pos.problem("var directive requires a host or @group and at least one key=value: %s", line))
+				continue
+			}
+			values, err := parseVars(fields[2:])
+			if err != nil {
+				problems = append(problems, pos.problem("invalid var directive: %v", err))
+				continue
+			}
+			c.vars = append(c.vars, varRule{hostRule: c.addRule(fields[1], pos), values: values})
+
+		case "repo":
+			if len(fields) != 3 {
+				problems = append(problems, pos.problem("repo directive requires a host or @group and exactly one path: %s", line))
+				continue
+			}
+			c.repos = append(c.repos, repoRule{hostRule: c.addRule(fields[1], pos), settings: RepoSettings{Path: fields[2]}})
+
+		case "rebuild":
+			if len(fields) < 3 {
+				problems = append(problems, pos.problem("rebuild directive requires a host or @group and a command: %s", line))
+				continue
+			}
+			c.repos = append(c.repos, repoRule{hostRule: c.addRule(fields[1], pos), settings: RepoSettings{Rebuild: strings.Join(fields[2:], " ")}})
+
+		case "remote":
+			if len(fields) != 3 && len(fields) != 4 {
+				problems = append(problems, pos.problem("remote directive requires a host or @group, a git remote and an optional branch: %s", line))
+				continue
+			}
+			settings := RepoSettings{Remote: fields[2]}
+			if len(fields) == 4 {
+				settings.Branch = fields[3]
+			}
+			c.repos = append(c.repos, repoRule{hostRule: c.addRule(fields[1], pos), settings: settings})
+
+		case "host":
+			if len(fields) < 3 {
+				problems = append(problems, pos.problem("host directive requires a host or @group and at least one attribute: %s", line))
+				continue
+			}
+			settings, err := parseHostSettings(fields[2:])
+			if err != nil {
+				problems = append(problems, pos.problem("invalid host directive: %v", err))
+				continue
+			}
+			c.hostSettings = append(c.hostSettings, hostSettingsRule{hostRule: c.addRule(fields[1], pos), settings: settings})
+
+		case "column":
+			if len(fields) < 3 {
+				problems = append(problems, pos.problem("column directive requires a name and a command: %s", line))
+				continue
+			}
+			column, err := c.parseColumn(fields[1:], pos)
+			if err != nil {
+				problems = append(problems, pos.problem("invalid column directive: %v", err))
+				continue
+			}
+			c.columns = append(c.columns, column)
+
+		case "threshold":
+			if len(fields) < 4 {
+				problems = append(problems, pos.problem("threshold directive requires a metric, a warning and a critical level: %s", line))
+				continue
+			}
+			threshold, err := c.parseThreshold(fields[1:], pos)
+			if err != nil {
+				problems = append(problems, pos.problem("invalid threshold directive: %v", err))
+				continue
+			}
+			c.thresholds = append(c.thresholds, threshold)
+
+		case "confirm_threshold":
+			if len(fields) != 2 {
+				problems = append(problems, pos.problem("confirm_threshold directive requires exactly one number: %s", line))
+				continue
+			}
+			threshold, err := strconv.Atoi(fields[1])
+			if err != nil || threshold < 0 {
+				problems = append(problems, pos.problem("invalid confirm_threshold: %s", fields[1]))
+				continue
+			}
+			c.confirmThreshold = threshold
+
+		case "source":
+			src, err := parseSource(fields[1:], filepath.Dir(path), pos)
+			if err != nil {
+				problems = append(problems, pos.problem("%v", err))
+				continue
+			}
+			c.sources = append(c.sources, src)
+
+		case "cache_ttl":
+			if len(fields) != 2 {
+				problems = append(problems, pos.problem("cache_ttl directive requires exactly one duration: %s", line))
+				continue
+			}
+			ttl, err := time.ParseDuration(fields[1])
+			if err != nil {
+				problems = append(problems, pos.problem("invalid cache_ttl: %v", err))
+				continue
+			}
+			c.cacheTTL = ttl
 
 		default:
-			return nil, fmt.Errorf("unknown directive '%s' on line %d: %s", fields[0], lineNum, line)
+			problems = append(problems, pos.problem("unknown directive '%s': %s", fields[0], line))
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading config file: %v", err)
+		return nil, fmt.Errorf("error reading config file %s: %v", path, err)
 	}
+	return problems, nil
+}
 
-	// Validate that default group exists if specified
-	if config.DefaultGroup != "" {
-		if _, exists := config.Groups[config.DefaultGroup]; !exists {
-			return nil, fmt.Errorf("default group '%s' is not defined", config.DefaultGroup)
+// parseInclude parses the files matched by an include directive at pos.
+// Relative patterns are resolved against the directory of the including file.
+func (c *HostConfig) parseInclude(pattern string, pos position, stack []string) []Problem {
+	pattern = resolvePath(pattern, filepath.Dir(pos.file))
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return []Problem{pos.problem("invalid include pattern %s: %v", pattern, err)}
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return []Problem{pos.problem("included file %s does not exist", pattern)}
+	}
+
+	var problems []Problem
+	for _, match := range matches {
+		if containsString(stack, match) {
+			cycle := append(append([]string(nil), stack...), match)
+			problems = append(problems, pos.problem("include cycle: %s", strings.Join(cycle, " -> ")))
+			continue
 		}
+
+		fileProblems, err := c.parseFile(match, stack)
+		if err != nil {
+			problems = append(problems, pos.problem("%v", err))
+			continue
+		}
+		problems = append(problems, fileProblems...)
 	}
+	return problems
+}
 
-	return config, nil
+// Files returns the config files that were read, in the order they were
+// first read
+func (c *HostConfig) Files() []string {
+	return c.files
+}
+
+// addGroup records a group definition from the config file. A repeated
+// definition replaces the earlier one.
+func (c *HostConfig) addGroup(name string, members []string, pos position) {
+	if previous, exists := c.groupPos[name]; exists {
+		c.lint = append(c.lint, pos.problem("group '%s' is already defined at %s:%d and is overwritten", name, previous.file, previous.line))
+	}
+
+	seen := make(map[string]bool)
+	for _, member := range members {
+		if seen[member] {
+			c.lint = append(c.lint, pos.problem("'%s' is listed more than once in group '%s'", member, name))
+		}
+		seen[member] = true
+
+		if !strings.HasPrefix(member, "@") {
+			if _, known := c.hostPos[member]; !known {
+				c.hostPos[member] = pos
+			}
+		}
+	}
+
+	if _, exists := c.Groups[name]; !exists {
+		c.groupOrder = append(c.groupOrder, name)
+	}
+	c.Groups[name] = members
+	c.groupPos[name] = pos
+}
+
+// GroupNames returns the names of all groups in the order they were first
+// defined, with groups from inventory sources after those from the config file
+func (c *HostConfig) GroupNames() []string {
+	names := append([]string(nil), c.groupOrder...)
+	if len(names) == len(c.Groups) {
+		return names
+	}
+
+	// Groups added without going through addGroup or merge
+	var rest []string
+	for name := range c.Groups {
+		if !containsString(names, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // ResolveHosts resolves a list of host arguments (which may include @group syntax)
 // into a flat list of hostnames. If no arguments are provided and a default group
-// is configured, it returns the hosts from the default group.
+// is configured, it returns the hosts from the default group. Hosts in
+// maintenance are left out of group expansions unless IncludeMaintenance is set.
 func (c *HostConfig) ResolveHosts(args []string) ([]string, error) {
+	hosts, _, err := c.ResolveHostsSkipping(args)
+	return hosts, err
+}
+
+// ResolveHostsSkipping is like ResolveHosts but also returns the hosts in
+// maintenance that were left out. Hosts named explicitly are never left out.
+func (c *HostConfig) ResolveHostsSkipping(args []string) ([]string, []string, error) {
 	// If no arguments and we have a default group, use it
 	if len(args) == 0 && c.DefaultGroup != "" {
-		if hosts, exists := c.Groups[c.DefaultGroup]; exists {
-			return hosts, nil
+		hosts, err := c.ExpandSelector("@" + c.DefaultGroup)
+		if err != nil {
+			return nil, nil, err
 		}
+		kept, skipped := c.skipMaintenance(hosts, nil)
+		return kept, skipped, nil
 	}
 
 	// If no arguments and no default group, return empty (caller should handle)
 	if len(args) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	var resolvedHosts []string
 	seenHosts := make(map[string]bool) // Track duplicates
+	explicit := make(map[string]bool)
 
 	for _, arg := range args {
 		if strings.HasPrefix(arg, "@") {
-			// Group reference
-			groupName := arg[1:]
-			if groupName == "" {
-				return nil, fmt.Errorf("empty group name: %s", arg)
-			}
-
-			hosts, exists := c.Groups[groupName]
-			if !exists {
-				return nil, fmt.Errorf("unknown group: %s", groupName)
+			// Group or class reference
+			hosts, err := c.ExpandSelector(arg)
+			if err != nil {
+				return nil, nil, err
 			}
 
 			// Add hosts from group, avoiding duplicates
@@ -148,6 +671,7 @@ func (c *HostConfig) ResolveHosts(args []string) ([]string, error) {
 			}
 		} else {
 			// Individual host
+			explicit[arg] = true
 			if !seenHosts[arg] {
 				resolvedHosts = append(resolvedHosts, arg)
 				seenHosts[arg] = true
@@ -155,5 +679,135 @@ func (c *HostConfig) ResolveHosts(args []string) ([]string, error) {
 		}
 	}
 
-	return resolvedHosts, nil
+	kept, skipped := c.skipMaintenance(resolvedHosts, explicit)
+	return kept, skipped, nil
+}
+
+// skipMaintenance splits hosts into those to keep and those in maintenance
+// that are not in explicit
+func (c *HostConfig) skipMaintenance(hosts []string, explicit map[string]bool) ([]string, []string) {
+	if c.IncludeMaintenance || len(c.Maintenance) == 0 {
+		return hosts, nil
+	}
+
+	var kept, skipped []string
+	for _, host := range hosts {
+		if _, inMaintenance := c.Maintenance[host]; inMaintenance && !explicit[host] {
+			skipped = append(skipped, host)
+		} else {
+			kept = append(kept, host)
+		}
+	}
+	return kept, skipped
+}
+
+// ExpandSelector returns the hosts referenced by a single @group or
+// @class:<name> selector. Groups may list other groups as @group members,
+// which are expanded recursively.
+func (c *HostConfig) ExpandSelector(selector string) ([]string, error) {
+	return c.expandSelector(selector, make(map[string]bool))
+}
+
+func (c *HostConfig) expandSelector(selector string, visiting map[string]bool) ([]string, error) {
+	name := strings.TrimPrefix(selector, "@")
+	if name == "" {
+		return nil, fmt.Errorf("empty group name: %s", selector)
+	}
+
+	if strings.HasPrefix(name, classPrefix) {
+		return c.hostsWithClass(strings.TrimPrefix(name, classPrefix))
+	}
+
+	members, exists := c.Groups[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown group: %s", name)
+	}
+	if visiting[name] {
+		return nil, fmt.Errorf("group cycle detected at @%s", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	var hosts []string
+	for _, member := range members {
+		if !strings.HasPrefix(member, "@") {
+			hosts = appendUnique(hosts, member)
+			continue
+		}
+
+		nested, err := c.expandSelector(member, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("%v (referenced by group %s)", err, name)
+		}
+		hosts = appendUnique(hosts, nested...)
+	}
+	return hosts, nil
+}
+
+// AllHosts returns every known host: hosts listed directly in a group, ordered by
+// group definition and then by position within the group, followed by the
+// remaining hosts discovered by inventory sources.
+func (c *HostConfig) AllHosts() []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, name := range c.GroupNames() {
+		for _, host := range c.Groups[name] {
+			if !strings.HasPrefix(host, "@") && !seen[host] {
+				hosts = append(hosts, host)
+				seen[host] = true
+			}
+		}
+	}
+	for _, host := range c.Hosts {
+		if !seen[host] {
+			hosts = append(hosts, host)
+			seen[host] = true
+		}
+	}
+	return hosts
+}
+
+// Membership describes why a host belongs to a group
+type Membership struct {
+	Group string
+	// Via is the nested group through which the host is included, or empty
+	// if the group lists the host directly
+	Via string
+}
+
+// MemberOf returns every group that contains host, directly or through nested
+// groups, in definition order
+func (c *HostConfig) MemberOf(host string) []Membership {
+	var memberships []Membership
+	for _, name := range c.GroupNames() {
+		hosts, err := c.ExpandSelector("@" + name)
+		if err != nil || !containsString(hosts, host) {
+			continue
+		}
+
+		membership := Membership{Group: name}
+		if !containsString(c.Groups[name], host) {
+			for _, member := range c.Groups[name] {
+				if !strings.HasPrefix(member, "@") {
+					continue
+				}
+				if nested, err := c.ExpandSelector(member); err == nil && containsString(nested, host) {
+					membership.Via = strings.TrimPrefix(member, "@")
+					break
+				}
+			}
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships
+}
+
+// GroupsOf returns the names of the groups that contain host, directly or
+// through nested groups, in definition order
+func (c *HostConfig) GroupsOf(host string) []string {
+	var groups []string
+	for _, membership := range c.MemberOf(host) {
+		groups = append(groups, membership.Group)
+	}
+	return groups
 }
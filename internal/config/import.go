@@ -0,0 +1,405 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ansibleRange matches a numeric or alphabetic host range such as web[01:10]
+var ansibleRange = regexp.MustCompile(`\[([0-9]+|[a-z]):([0-9]+|[a-z])\]`)
+
+// ansibleINI is an Ansible INI inventory as read by ImportAnsibleINI
+type ansibleINI struct {
+	order     []string
+	hosts     map[string][]string
+	children  map[string][]string
+	groupVars map[string][][2]string
+	hostVars  map[string][][2]string
+	hostOrder []string
+}
+
+// ImportAnsibleINI converts an Ansible INI inventory to hladmin config
+// directives. [group] sections become group directives, [group:children]
+// become @group members, Ansible connection variables become host directives
+// and other host and [group:vars] variables become var directives. Entries
+// that cannot be represented are returned as warnings.
+func ImportAnsibleINI(r io.Reader) (string, []string, error) {
+	inv := &ansibleINI{
+		hosts:     make(map[string][]string),
+		children:  make(map[string][]string),
+		groupVars: make(map[string][][2]string),
+		hostVars:  make(map[string][][2]string),
+	}
+	var warnings []string
+
+	section, kind := "ungrouped", ""
+	inv.addGroup(section)
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind, _ = strings.Cut(line[1:len(line)-1], ":")
+			if kind != "" && kind != "children" && kind != "vars" {
+				warnings = append(warnings, fmt.Sprintf("line %d: unknown section type '%s'", lineNumber, kind))
+			}
+			inv.addGroup(section)
+			continue
+		}
+
+		fields, err := splitAnsibleFields(line)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("line %d: %v", lineNumber, err))
+			continue
+		}
+
+		switch kind {
+		case "children":
+			inv.addGroup(fields[0])
+			inv.children[section] = appendUnique(inv.children[section], fields[0])
+		case "vars":
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				warnings = append(warnings, fmt.Sprintf("line %d: expected key=value in [%s:vars]", lineNumber, section))
+				continue
+			}
+			value, err := unquoteAnsible(strings.TrimSpace(value))
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("line %d: %v", lineNumber, err))
+				continue
+			}
+			inv.groupVars[section] = append(inv.groupVars[section], [2]string{strings.TrimSpace(key), value})
+		case "":
+			hosts, err := expandAnsibleRange(fields[0])
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("line %d: %v", lineNumber, err))
+				continue
+			}
+			group := section
+			if group == ansibleAllGroup {
+				group = "ungrouped"
+				inv.addGroup(group)
+			}
+			inv.hosts[group] = appendUnique(inv.hosts[group], hosts...)
+			for _, host := range hosts {
+				if _, known := inv.hostVars[host]; !known {
+					inv.hostOrder = append(inv.hostOrder, host)
+					inv.hostVars[host] = nil
+				}
+				for _, field := range fields[1:] {
+					key, value, found := strings.Cut(field, "=")
+					if !found {
+						warnings = append(warnings, fmt.Sprintf("line %d: expected key=value, got '%s'", lineNumber, field))
+						continue
+					}
+					inv.hostVars[host] = append(inv.hostVars[host], [2]string{key, value})
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("error reading inventory: %v", err)
+	}
+
+	output, moreWarnings := inv.directives()
+	return output, append(warnings, moreWarnings...), nil
+}
+
+func (inv *ansibleINI) addGroup(name string) {
+	for _, existing := range inv.order {
+		if existing == name {
+			return
+		}
+	}
+	inv.order = append(inv.order, name)
+}
+
+// directives writes the inventory as hladmin config directives
+func (inv *ansibleINI) directives() (string, []string) {
+	var b strings.Builder
+	var warnings []string
+
+	// Ansible's implicit all group contains every host, so it is only
+	// defined when variables are set on it
+	if len(inv.groupVars[ansibleAllGroup]) > 0 {
+		inv.hosts[ansibleAllGroup] = appendUnique(inv.hosts[ansibleAllGroup], inv.hostOrder...)
+	}
+
+	// Like Ansible's ungrouped group, ungrouped only keeps hosts that no
+	// other group lists
+	grouped := make(map[string]bool)
+	for group, hosts := range inv.hosts {
+		if group == "ungrouped" || group == ansibleAllGroup {
+			continue
+		}
+		for _, host := range hosts {
+			grouped[host] = true
+		}
+	}
+	var ungrouped []string
+	for _, host := range inv.hosts["ungrouped"] {
+		if !grouped[host] {
+			ungrouped = append(ungrouped, host)
+		}
+	}
+	inv.hosts["ungrouped"] = ungrouped
+
+	// Groups without members cannot be defined, and neither can groups whose
+	// only children are such groups
+	defined := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, group := range inv.order {
+			if defined[group] {
+				continue
+			}
+			hasMembers := len(inv.hosts[group]) > 0
+			for _, child := range inv.children[group] {
+				hasMembers = hasMembers || defined[child]
+			}
+			if hasMembers {
+				defined[group] = true
+				changed = true
+			}
+		}
+	}
+
+	for _, group := range inv.order {
+		if !defined[group] {
+			if len(inv.groupVars[group]) > 0 || len(inv.children[group]) > 0 {
+				warnings = append(warnings, fmt.Sprintf("group '%s' has no hosts and was skipped", group))
+			}
+			continue
+		}
+		members := append([]string{}, inv.hosts[group]...)
+		for _, child := range inv.children[group] {
+			if defined[child] {
+				members = append(members, "@"+child)
+			}
+		}
+		fmt.Fprintf(&b, "group %s %s\n", group, strings.Join(members, " "))
+	}
+
+	var vars []string
+	for _, group := range inv.order {
+		if defined[group] {
+			settings, rest := hostDirective("@"+group, inv.groupVars[group], &warnings)
+			vars = append(vars, settings...)
+			vars = append(vars, varDirective("@"+group, rest, &warnings)...)
+		}
+	}
+	for _, host := range inv.hostOrder {
+		settings, rest := hostDirective(host, inv.hostVars[host], &warnings)
+		vars = append(vars, settings...)
+		vars = append(vars, varDirective(host, rest, &warnings)...)
+	}
+	if len(vars) > 0 {
+		b.WriteString("\n")
+		for _, line := range vars {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String(), warnings
+}
+
+// ansibleConnectionVars maps the Ansible connection variables written by
+// inventory export to host attributes
+var ansibleConnectionVars = map[string]string{
+	"ansible_host":                 "address",
+	"ansible_user":                 "user",
+	"ansible_port":                 "port",
+	"ansible_ssh_private_key_file": "identity",
+}
+
+// hostDirective returns the host directive setting the connection variables
+// among pairs on target, and the remaining variables. Connection variables
+// that cannot be represented are reported as warnings.
+func hostDirective(target string, pairs [][2]string, warnings *[]string) ([]string, [][2]string) {
+	var fields []string
+	var rest [][2]string
+	for _, pair := range pairs {
+		var attributes []string
+		if attribute, ok := ansibleConnectionVars[pair[0]]; ok {
+			attributes = []string{attribute + "=" + pair[1]}
+		} else if pair[0] == "ansible_ssh_common_args" {
+			var err error
+			if attributes, err = sshArgsAttributes(pair[1]); err != nil {
+				*warnings = append(*warnings, fmt.Sprintf("variable '%s' of %s was skipped: %v", pair[0], target, err))
+				continue
+			}
+		} else {
+			rest = append(rest, pair)
+			continue
+		}
+
+		// The config format splits attributes on whitespace
+		_, err := parseHostSettings(attributes)
+		for _, attribute := range attributes {
+			if strings.ContainsAny(attribute, " \t") {
+				err = fmt.Errorf("whitespace in '%s'", attribute)
+			}
+		}
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("variable '%s' of %s has an invalid value and was skipped", pair[0], target))
+			continue
+		}
+		fields = append(fields, attributes...)
+	}
+	if len(fields) == 0 {
+		return nil, rest
+	}
+	return []string{"host " + target + " " + strings.Join(fields, " ")}, rest
+}
+
+// sshArgsAttributes converts the -o options of ansible_ssh_common_args to
+// host attributes
+func sshArgsAttributes(args string) ([]string, error) {
+	var attributes []string
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		option := strings.TrimPrefix(fields[i], "-o")
+		if option == fields[i] {
+			return nil, fmt.Errorf("unsupported ssh argument '%s'", fields[i])
+		}
+		if option == "" {
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("missing value for -o")
+			}
+			i++
+			option = fields[i]
+		}
+		if name, value, found := strings.Cut(option, "="); found && strings.EqualFold(name, "ProxyJump") {
+			attributes = append(attributes, "proxy_jump="+value)
+		} else {
+			attributes = append(attributes, "option="+option)
+		}
+	}
+	return attributes, nil
+}
+
+// varDirective returns the var directive setting pairs on target. Values
+// containing whitespace cannot be written in the config format and are
+// reported as warnings.
+func varDirective(target string, pairs [][2]string, warnings *[]string) []string {
+	var fields []string
+	for _, pair := range pairs {
+		if pair[0] == "" || pair[1] == "" || strings.ContainsAny(pair[1], " \t") {
+			*warnings = append(*warnings, fmt.Sprintf("variable '%s' of %s has an empty or whitespace value and was skipped", pair[0], target))
+			continue
+		}
+		fields = append(fields, pair[0]+"="+pair[1])
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return []string{"var " + target + " " + strings.Join(fields, " ")}
+}
+
+// splitAnsibleFields splits a line on whitespace, keeping quoted values
+// together and removing their quotes
+func splitAnsibleFields(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	inField := false
+
+scan:
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		case r == '#' && !inField:
+			// The rest of the line is a comment
+			break scan
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty entry")
+	}
+	return fields, nil
+}
+
+// unquoteAnsible removes the quotes around a [group:vars] value
+func unquoteAnsible(value string) (string, error) {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1], nil
+	}
+	if strings.HasPrefix(value, "\"") || strings.HasPrefix(value, "'") {
+		return "", fmt.Errorf("unterminated quote")
+	}
+	return value, nil
+}
+
+// expandAnsibleRange expands the first [start:end] range in a host pattern,
+// recursively expanding any that follow
+func expandAnsibleRange(pattern string) ([]string, error) {
+	match := ansibleRange.FindStringSubmatchIndex(pattern)
+	if match == nil {
+		if strings.ContainsAny(pattern, "[]") {
+			return nil, fmt.Errorf("unsupported host pattern '%s'", pattern)
+		}
+		return []string{pattern}, nil
+	}
+
+	prefix, suffix := pattern[:match[0]], pattern[match[1]:]
+	start, end := pattern[match[2]:match[3]], pattern[match[4]:match[5]]
+
+	var values []string
+	if startNum, err := strconv.Atoi(start); err == nil {
+		endNum, err := strconv.Atoi(end)
+		if err != nil || endNum < startNum {
+			return nil, fmt.Errorf("invalid range in host pattern '%s'", pattern)
+		}
+		for i := startNum; i <= endNum; i++ {
+			values = append(values, fmt.Sprintf("%0*d", len(start), i))
+		}
+	} else {
+		if len(end) != 1 || end[0] < start[0] {
+			return nil, fmt.Errorf("invalid range in host pattern '%s'", pattern)
+		}
+		for c := start[0]; c <= end[0]; c++ {
+			values = append(values, string(c))
+		}
+	}
+
+	var hosts []string
+	for _, value := range values {
+		expanded, err := expandAnsibleRange(prefix + value + suffix)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, expanded...)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAnsibleRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain host", pattern: "web1", want: []string{"web1"}},
+		{name: "numeric", pattern: "web[1:3]", want: []string{"web1", "web2", "web3"}},
+		{name: "zero-padded", pattern: "web[08:10].lan", want: []string{"web08.lan", "web09.lan", "web10.lan"}},
+		{name: "alphabetic", pattern: "db-[a:c]", want: []string{"db-a", "db-b", "db-c"}},
+		{name: "nested", pattern: "rack[1:2]-node[a:b]", want: []string{"rack1-nodea", "rack1-nodeb", "rack2-nodea", "rack2-nodeb"}},
+		{name: "single value", pattern: "web[5:5]", want: []string{"web5"}},
+		{name: "descending", pattern: "web[3:1]", wantErr: true},
+		{name: "mixed bounds", pattern: "web[1:c]", wantErr: true},
+		{name: "stride", pattern: "web[1:9:2]", wantErr: true},
+		{name: "unclosed", pattern: "web[1:3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandAnsibleRange(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandAnsibleRange(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandAnsibleRange(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAnsibleFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "host only", line: "web1", want: []string{"web1"}},
+		{name: "variables", line: "web1  ansible_host=10.0.0.1\tport=22", want: []string{"web1", "ansible_host=10.0.0.1", "port=22"}},
+		{name: "double quotes", line: `web1 motd="hello world"`, want: []string{"web1", "motd=hello world"}},
+		{name: "single quotes", line: `web1 motd='say "hi"'`, want: []string{"web1", `motd=say "hi"`}},
+		{name: "empty quotes", line: `web1 note=""`, want: []string{"web1", "note="}},
+		{name: "comment", line: "web1 # primary", want: []string{"web1"}},
+		{name: "hash inside value", line: "web1 color=#fff", want: []string{"web1", "color=#fff"}},
+		{name: "unterminated quote", line: `web1 motd="hello`, wantErr: true},
+		{name: "only comment", line: "# nothing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitAnsibleFields(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitAnsibleFields(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAnsibleFields(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportAnsibleINI(t *testing.T) {
+	tests := []struct {
+		name         string
+		inventory    string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name: "groups and ranges",
+			inventory: `
+[web]
+web[01:02]
+
+[db]
+db-[a:b]
+`,
+			want: "group web web01 web02\ngroup db db-a db-b\n",
+		},
+		{
+			name: "ungrouped and all hosts",
+			inventory: `
+lonely
+[all]
+other
+`,
+			want: "group ungrouped lonely other\n",
+		},
+		{
+			name: "children",
+			inventory: `
+[web]
+web1
+
+[db]
+db1
+
+[prod:children]
+web
+db
+`,
+			want: "group web web1\ngroup db db1\ngroup prod @web @db\n",
+		},
+		{
+			name: "empty child groups",
+			inventory: `
+[web]
+web1
+
+[empty]
+
+[staging:children]
+empty
+
+[prod:children]
+web
+empty
+staging
+`,
+			want:         "group web web1\ngroup prod @web\n",
+			wantWarnings: []string{"group 'staging' has no hosts and was skipped"},
+		},
+		{
+			name: "variables",
+			inventory: `
+[web]
+web1 ansible_host=10.0.0.1 motd="hello world"
+
+[web:vars]
+backup_dir = '/srv/backup'
+`,
+			want: "group web web1\n\nvar @web backup_dir=/srv/backup\nhost web1 address=10.0.0.1\n",
+			wantWarnings: []string{
+				"variable 'motd' of web1 has an empty or whitespace value and was skipped",
+			},
+		},
+		{
+			name: "connection variables",
+			inventory: `
+[web]
+web1 ansible_host=10.0.0.1 ansible_user=admin ansible_port=2222 ansible_ssh_private_key_file=/keys/id role=web
+web2 ansible_ssh_common_args="-o ProxyJump=bastion -oServerAliveInterval=30"
+
+[web:vars]
+ansible_user=deploy
+`,
+			want: `group web web1 web2
+
+host @web user=deploy
+host web1 address=10.0.0.1 user=admin port=2222 identity=/keys/id
+var web1 role=web
+host web2 proxy_jump=bastion option=ServerAliveInterval=30
+`,
+		},
+		{
+			name: "invalid connection variables",
+			inventory: `
+[web]
+web1 ansible_port=ssh ansible_user="John Doe" ansible_ssh_common_args="-v"
+`,
+			want: "group web web1\n",
+			wantWarnings: []string{
+				"variable 'ansible_port' of web1 has an invalid value and was skipped",
+				"variable 'ansible_user' of web1 has an invalid value and was skipped",
+				"variable 'ansible_ssh_common_args' of web1 was skipped: unsupported ssh argument '-v'",
+			},
+		},
+		{
+			name: "grouped hosts listed under all",
+			inventory: `
+[all]
+web1
+lonely
+
+[web]
+web1
+`,
+			want: "group ungrouped lonely\ngroup web web1\n",
+		},
+		{
+			name: "all variables define the all group",
+			inventory: `
+[web]
+web1
+
+[all:vars]
+domain=lan
+`,
+			want: "group web web1\ngroup all web1\n\nvar @all domain=lan\n",
+		},
+		{
+			name: "invalid entries",
+			inventory: `
+[web]
+web[3:1]
+web2 broken
+[web:other]
+`,
+			want: "group web web2\n",
+			wantWarnings: []string{
+				"line 3: invalid range in host pattern 'web[3:1]'",
+				"line 4: expected key=value, got 'broken'",
+				"line 5: unknown section type 'other'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ImportAnsibleINI(strings.NewReader(tt.inventory))
+			if err != nil {
+				t.Fatalf("ImportAnsibleINI() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ImportAnsibleINI() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("ImportAnsibleINI() warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+// parseTestConfig parses config as the contents of a hosts file
+func parseTestConfig(t *testing.T, config string) *HostConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newHostConfig(nil)
+	problems, err := c.parseConfigFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("invalid config: %v\n%s", problems, config)
+	}
+	return c
+}
+
+func TestAnsibleINIRoundTrip(t *testing.T) {
+	original := parseTestConfig(t, `group servers server1 server2
+group desktops desktop1
+group all @servers @desktops
+group storage server2 nas1
+var @servers backup_dir=/srv/backup
+var server1 role=web
+host @servers user=admin
+host server1 address=10.0.0.11 port=2222 identity=/keys/homelab
+host server2 proxy_jump=bastion option=ServerAliveInterval=30
+`)
+
+	var exported strings.Builder
+	if err := original.Export(&exported, "ansible-ini"); err != nil {
+		t.Fatal(err)
+	}
+	imported, warnings, err := ImportAnsibleINI(strings.NewReader(exported.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("import warnings: %q", warnings)
+	}
+	roundTrip := parseTestConfig(t, imported)
+
+	if got, want := roundTrip.AllHosts(), original.AllHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts = %v, want %v", got, want)
+	}
+	for _, group := range []string{"servers", "desktops", "storage"} {
+		got, _ := roundTrip.ExpandSelector("@" + group)
+		want, _ := original.ExpandSelector("@" + group)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("@%s = %v, want %v", group, got, want)
+		}
+	}
+	if _, exists := roundTrip.Groups["ungrouped"]; exists {
+		t.Errorf("import added group ungrouped: %v", roundTrip.Groups["ungrouped"])
+	}
+	for _, host := range original.AllHosts() {
+		if got, want := roundTrip.HostSettings(host), original.HostSettings(host); !reflect.DeepEqual(got, want) {
+			t.Errorf("settings of %s = %+v, want %+v", host, got, want)
+		}
+		if got, want := roundTrip.Vars(host), original.Vars(host); !reflect.DeepEqual(got, want) {
+			t.Errorf("vars of %s = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Maintenance records why and until when a host is in maintenance mode
+type Maintenance struct {
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+	// Until is when maintenance ends on its own; zero means it lasts until it
+	// is turned off
+	Until time.Time `json:"until,omitempty"`
+}
+
+// Expired reports whether the maintenance has ended by now
+func (m Maintenance) Expired(now time.Time) bool {
+	return !m.Until.IsZero() && !now.Before(m.Until)
+}
+
+// includeMaintenance keeps hosts in maintenance in group expansions. It is
+// set from a command line flag.
+var includeMaintenance bool
+
+// SetIncludeMaintenance makes loaded configurations keep hosts in maintenance
+// when groups are expanded
+func SetIncludeMaintenance(include bool) {
+	includeMaintenance = include
+}
+
+// GetMaintenancePath returns the full path to the maintenance state file
+func GetMaintenancePath() string {
+	stateDir := getStateDir()
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, "maintenance.json")
+}
+
+// LoadMaintenance loads the hosts in maintenance mode. Expired entries are
+// left out, and a missing state file yields an empty map.
+func LoadMaintenance() (map[string]Maintenance, error) {
+	maintenance := make(map[string]Maintenance)
+
+	statePath := GetMaintenancePath()
+	if statePath == "" {
+		return maintenance, nil
+	}
+
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return maintenance, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maintenance state %s: %v", statePath, err)
+	}
+
+	if err := json.Unmarshal(data, &maintenance); err != nil {
+		return nil, fmt.Errorf("failed to parse maintenance state %s: %v", statePath, err)
+	}
+
+	now := time.Now()
+	for host, entry := range maintenance {
+		if entry.Expired(now) {
+			delete(maintenance, host)
+		}
+	}
+	return maintenance, nil
+}
+
+// SaveMaintenance writes the hosts in maintenance mode to the state file
+func SaveMaintenance(maintenance map[string]Maintenance) error {
+	statePath := GetMaintenancePath()
+	if statePath == "" {
+		return fmt.Errorf("cannot determine state directory: neither XDG_STATE_HOME nor HOME is set")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(maintenance, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance state: %v", err)
+	}
+
+	if err := os.WriteFile(statePath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write maintenance state %s: %v", statePath, err)
+	}
+	return nil
+}
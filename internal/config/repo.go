@@ -0,0 +1,67 @@
+package config
+
+// Defaults used when no repo, rebuild or remote directive applies to a host
+const (
+	DefaultRepoPath       = "$HOME/nix-config"
+	DefaultRebuildCommand = "./rebuild.sh"
+)
+
+// RepoSettings describes where a host keeps its configuration repository and
+// how it is updated and rebuilt
+type RepoSettings struct {
+	// Path is the repository location, expanded by the host's shell
+	Path string
+	// Rebuild is the command that rebuilds the system, run inside Path
+	Rebuild string
+	// Remote and Branch are passed to git pull when set
+	Remote string
+	Branch string
+}
+
+// repoRule overrides repository settings for a host or group. Empty fields
+// leave the setting unchanged.
+type repoRule struct {
+	hostRule
+	settings RepoSettings
+}
+
+// Repo returns the repository settings of host. Group rules apply before host
+// rules, and rules of the same kind apply in the order they appear in the
+// config.
+func (c *HostConfig) Repo(host string) RepoSettings {
+	settings := RepoSettings{
+		Path:    DefaultRepoPath,
+		Rebuild: DefaultRebuildCommand,
+	}
+
+	for _, groupRules := range []bool{true, false} {
+		for _, rule := range c.repos {
+			if rule.isGroup() != groupRules || !rule.appliesTo(c, host) {
+				continue
+			}
+			if rule.settings.Path != "" {
+				settings.Path = rule.settings.Path
+			}
+			if rule.settings.Rebuild != "" {
+				settings.Rebuild = rule.settings.Rebuild
+			}
+			if rule.settings.Remote != "" {
+				settings.Remote = rule.settings.Remote
+				settings.Branch = rule.settings.Branch
+			}
+		}
+	}
+	return settings
+}
+
+// PullCommand returns the git pull invocation for the settings
+func (s RepoSettings) PullCommand() string {
+	command := "git pull"
+	if s.Remote != "" {
+		command += " " + s.Remote
+		if s.Branch != "" {
+			command += " " + s.Branch
+		}
+	}
+	return command
+}
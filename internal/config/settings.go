@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultConfirmThreshold is the number of hosts above which mutating
+// commands ask for confirmation when no confirm_threshold directive is given
+const defaultConfirmThreshold = 5
+
+// HostSettings holds the attributes set on a host by host directives
+type HostSettings struct {
+	// Protected hosts require their name to be typed before mutating
+	// commands run on them
+	Protected bool
+
+	// SSH connection settings. Empty fields leave the setting to
+	// ~/.ssh/config.
+	Address   string
+	User      string
+	Port      int
+	Identity  string
+	ProxyJump string
+	// Options holds extra ssh options as Key=Value, one per key
+	Options []string
+}
+
+// hostSettingsRule sets attributes on a host or group
+type hostSettingsRule struct {
+	hostRule
+	settings HostSettings
+}
+
+// parseHostSettings parses the attributes of a host directive: the protected
+// flag and key=value connection settings
+func parseHostSettings(fields []string) (HostSettings, error) {
+	var settings HostSettings
+	for _, field := range fields {
+		if field == "protected" {
+			settings.Protected = true
+			continue
+		}
+
+		key, value, found := strings.Cut(field, "=")
+		if !found || value == "" {
+			return settings, fmt.Errorf("unknown host attribute '%s'", field)
+		}
+		switch key {
+		case "address":
+			settings.Address = value
+		case "user":
+			settings.User = value
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				return settings, fmt.Errorf("invalid port '%s'", value)
+			}
+			settings.Port = port
+		case "identity":
+			settings.Identity = resolvePath(value, "")
+		case "proxy_jump":
+			settings.ProxyJump = value
+		case "option":
+			if name, optionValue, found := strings.Cut(value, "="); !found || name == "" || optionValue == "" {
+				return settings, fmt.Errorf("ssh option must be Key=Value, got '%s'", value)
+			}
+			settings.Options = append(settings.Options, value)
+		default:
+			return settings, fmt.Errorf("unknown host attribute '%s'", key)
+		}
+	}
+	return settings, nil
+}
+
+// HostSettings returns the attributes of host. Group rules apply before host
+// rules, and rules of the same kind apply in the order they appear in the
+// config.
+func (c *HostConfig) HostSettings(host string) HostSettings {
+	var settings HostSettings
+	for _, groupRules := range []bool{true, false} {
+		for _, rule := range c.hostSettings {
+			if rule.isGroup() != groupRules || !rule.appliesTo(c, host) {
+				continue
+			}
+			settings.merge(rule.settings)
+		}
+	}
+	return settings
+}
+
+// merge overrides the settings with those set in other
+func (s *HostSettings) merge(other HostSettings) {
+	if other.Protected {
+		s.Protected = true
+	}
+	if other.Address != "" {
+		s.Address = other.Address
+	}
+	if other.User != "" {
+		s.User = other.User
+	}
+	if other.Port != 0 {
+		s.Port = other.Port
+	}
+	if other.Identity != "" {
+		s.Identity = other.Identity
+	}
+	if other.ProxyJump != "" {
+		s.ProxyJump = other.ProxyJump
+	}
+	for _, option := range other.Options {
+		name, _, _ := strings.Cut(option, "=")
+		replaced := false
+		for i, existing := range s.Options {
+			if existingName, _, _ := strings.Cut(existing, "="); strings.EqualFold(existingName, name) {
+				s.Options[i] = option
+				replaced = true
+			}
+		}
+		if !replaced {
+			s.Options = append(s.Options, option)
+		}
+	}
+}
+
+// SSHOptions returns the ssh command line options that apply the connection
+// settings. They are passed as -o options so that ssh and scp accept them
+// alike, and take precedence over ~/.ssh/config.
+func (s HostSettings) SSHOptions() []string {
+	var options []string
+	add := func(name, value string) {
+		options = append(options, "-o", name+"="+value)
+	}
+	if s.Address != "" {
+		add("HostName", s.Address)
+	}
+	if s.User != "" {
+		add("User", s.User)
+	}
+	if s.Port != 0 {
+		add("Port", strconv.Itoa(s.Port))
+	}
+	if s.Identity != "" {
+		add("IdentityFile", s.Identity)
+	}
+	if s.ProxyJump != "" {
+		add("ProxyJump", s.ProxyJump)
+	}
+	for _, option := range s.Options {
+		options = append(options, "-o", option)
+	}
+	return options
+}
+
+// ConfirmThreshold returns the number of hosts a mutating command may target
+// without asking for confirmation. Zero disables the check.
+func (c *HostConfig) ConfirmThreshold() int {
+	return c.confirmThreshold
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCacheTTL is how long dynamic inventory results are reused before the
+// source is queried again
+const defaultCacheTTL = 10 * time.Minute
+
+// sshConfigTag marks an ssh_config comment that assigns groups to a Host block
+const sshConfigTag = "hladmin:"
+
+// source describes a dynamic inventory source declared in the config file
+type source struct {
+	kind string
+	path string
+	args []string
+	attr string
+	pos  position
+}
+
+// inventory is the set of hosts and groups contributed by a source. It is
+// also the JSON format that inventory scripts must print.
+type inventory struct {
+	Hosts  []string                     `json:"hosts"`
+	Groups map[string][]string          `json:"groups"`
+	Vars   map[string]map[string]string `json:"vars"`
+}
+
+// parseSource parses the arguments of a source directive
+func parseSource(fields []string, baseDir string, pos position) (source, error) {
+	src := source{kind: fields[0], pos: pos}
+
+	switch src.kind {
+	case "ssh_config":
+		if len(fields) > 2 {
+			return src, fmt.Errorf("ssh_config source takes at most one path")
+		}
+		src.path = "~/.ssh/config"
+		if len(fields) == 2 {
+			src.path = fields[1]
+		}
+	case "flake":
+		if len(fields) > 3 {
+			return src, fmt.Errorf("flake source takes at most a path and an attribute name")
+		}
+		src.path = "~/nix-config"
+		if len(fields) >= 2 {
+			src.path = fields[1]
+		}
+		if len(fields) == 3 {
+			src.attr = fields[2]
+		}
+	case "script":
+		if len(fields) < 2 {
+			return src, fmt.Errorf("script source requires a path")
+		}
+		src.path = fields[1]
+		src.args = fields[2:]
+	default:
+		return src, fmt.Errorf("unknown source type '%s'", src.kind)
+	}
+
+	src.path = resolvePath(src.path, baseDir)
+	return src, nil
+}
+
+// resolvePath expands a leading ~ and makes relative paths relative to baseDir
+func resolvePath(path, baseDir string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = filepath.Join(os.Getenv("HOME"), path[1:])
+	}
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	return path
+}
+
+// load returns the inventory provided by the source. Script and flake results
+// are cached for ttl; if the source fails, a stale cached result is used
+// instead and a warning is returned.
+func (s source) load(ttl time.Duration) (*inventory, string, error) {
+	switch s.kind {
+	case "ssh_config":
+		inv, err := parseSSHConfig(s.path)
+		return inv, "", err
+	case "flake":
+		return cachedInventory(s.cacheKey(), ttl, s.evalFlake)
+	default:
+		return cachedInventory(s.cacheKey(), ttl, s.runScript)
+	}
+}
+
+// cacheKey identifies the source in the inventory cache
+func (s source) cacheKey() string {
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{s.kind, s.path, s.attr}, s.args...), "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s source) runScript() (*inventory, error) {
+	cmd := exec.Command(s.path, s.args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("inventory script %s failed: %v: %s", s.path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var inv inventory
+	if err := json.Unmarshal(output, &inv); err != nil {
+		return nil, fmt.Errorf("inventory script %s printed invalid JSON: %v", s.path, err)
+	}
+	return &inv, nil
+}
+
+// flakeHost is the per-host information read from a flake configuration
+type flakeHost struct {
+	Groups []string          `json:"groups"`
+	Vars   map[string]string `json:"vars"`
+}
+
+// flakeExpr builds a Nix expression listing the nixosConfigurations and
+// darwinConfigurations outputs of the flake at path. When attr is set, the
+// groups list and vars attrset of config.<attr> are read for each host.
+func flakeExpr(path, attr string) string {
+	hostExpr := "{ }"
+	if attr != "" {
+		hostExpr = fmt.Sprintf("let h = c.config.%s or { }; in { groups = h.groups or [ ]; vars = h.vars or { }; }", strconv.Quote(attr))
+	}
+
+	return fmt.Sprintf(`let
+  flake = builtins.getFlake %s;
+  hosts = kind: builtins.mapAttrs (name: c: %s) (flake.${kind} or { });
+in
+{
+  nixos = hosts "nixosConfigurations";
+  darwin = hosts "darwinConfigurations";
+}`, nixString(path), hostExpr)
+}
+
+// nixString quotes s as a Nix string literal
+func nixString(s string) string {
+	return strings.ReplaceAll(strconv.Quote(s), "${", "\\${")
+}
+
+func (s source) evalFlake() (*inventory, error) {
+	cmd := exec.Command("nix", "eval", "--json", "--impure", "--expr", flakeExpr(s.path, s.attr))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate flake %s: %v: %s", s.path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var outputs map[string]map[string]flakeHost
+	if err := json.Unmarshal(output, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse flake outputs of %s: %v", s.path, err)
+	}
+
+	inv := &inventory{Groups: make(map[string][]string), Vars: make(map[string]map[string]string)}
+	for _, kind := range []string{"nixos", "darwin"} {
+		names := make([]string, 0, len(outputs[kind]))
+		for name := range outputs[kind] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			inv.Hosts = append(inv.Hosts, name)
+			inv.Groups[kind] = append(inv.Groups[kind], name)
+			for _, group := range outputs[kind][name].Groups {
+				inv.Groups[group] = append(inv.Groups[group], name)
+			}
+			if len(outputs[kind][name].Vars) > 0 {
+				inv.Vars[name] = outputs[kind][name].Vars
+			}
+		}
+	}
+	return inv, nil
+}
+
+// getInventoryCacheDir returns the directory holding cached source results
+func getInventoryCacheDir() string {
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "inventory")
+}
+
+// ClearInventoryCache removes all cached source results so that the next
+// LoadConfig queries every source again
+func ClearInventoryCache() error {
+	cacheDir := getInventoryCacheDir()
+	if cacheDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return fmt.Errorf("failed to clear inventory cache: %v", err)
+	}
+	return nil
+}
+
+// cachedInventory returns the cached inventory for key if it is younger than
+// ttl, and otherwise calls fetch and caches its result
+func cachedInventory(key string, ttl time.Duration, fetch func() (*inventory, error)) (*inventory, string, error) {
+	cacheDir := getInventoryCacheDir()
+	if cacheDir == "" {
+		inv, err := fetch()
+		return inv, "", err
+	}
+	cachePath := filepath.Join(cacheDir, key+".json")
+
+	var cached *inventory
+	if stat, err := os.Stat(cachePath); err == nil {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var inv inventory
+			if json.Unmarshal(data, &inv) == nil {
+				cached = &inv
+				if time.Since(stat.ModTime()) < ttl {
+					return cached, "", nil
+				}
+			}
+		}
+	}
+
+	inv, err := fetch()
+	if err != nil {
+		if cached != nil {
+			return cached, fmt.Sprintf("%v (using cached result)", err), nil
+		}
+		return nil, "", err
+	}
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode inventory cache: %v", err)
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, "", fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
+		return nil, "", fmt.Errorf("failed to write inventory cache %s: %v", cachePath, err)
+	}
+	return inv, "", nil
+}
+
+// parseSSHConfig collects the concrete host aliases declared by Host lines in
+// an ssh_config file. Comments of the form "# hladmin: group1 group2" inside a
+// Host block add the block's hosts to those groups.
+func parseSSHConfig(path string) (*inventory, error) {
+	inv := &inventory{Groups: make(map[string][]string)}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return inv, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ssh config %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var blockHosts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "#") {
+			comment := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			if strings.HasPrefix(comment, sshConfigTag) {
+				for _, group := range strings.Fields(strings.TrimPrefix(comment, sshConfigTag)) {
+					inv.Groups[group] = append(inv.Groups[group], blockHosts...)
+				}
+			}
+			continue
+		}
+
+		fields := strings.Fields(strings.ReplaceAll(line, "=", " "))
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "host":
+			blockHosts = nil
+			for _, pattern := range fields[1:] {
+				if strings.ContainsAny(pattern, "*?!") {
+					continue
+				}
+				blockHosts = append(blockHosts, pattern)
+				inv.Hosts = append(inv.Hosts, pattern)
+			}
+		case "match":
+			blockHosts = nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading ssh config %s: %v", path, err)
+	}
+	return inv, nil
+}
+
+// merge adds the hosts, groups and variables of inv, discovered by the source declared
+// at pos, to the configuration, keeping existing group members first and
+// skipping duplicates
+func (c *HostConfig) merge(inv *inventory, pos position) {
+	c.addHosts(pos, inv.Hosts...)
+
+	groups := make([]string, 0, len(inv.Groups))
+	for group := range inv.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		hosts := inv.Groups[group]
+		c.addHosts(pos, hosts...)
+		if _, exists := c.Groups[group]; !exists {
+			c.groupOrder = append(c.groupOrder, group)
+		}
+		c.Groups[group] = appendUnique(c.Groups[group], hosts...)
+	}
+
+	for host, vars := range inv.Vars {
+		if c.sourceVars[host] == nil {
+			c.sourceVars[host] = make(map[string]string)
+		}
+		for key, value := range vars {
+			c.sourceVars[host][key] = value
+		}
+	}
+}
+
+// addHosts records hosts discovered by the source declared at pos as known to
+// the inventory
+func (c *HostConfig) addHosts(pos position, hosts ...string) {
+	c.Hosts = appendUnique(c.Hosts, hosts...)
+	for _, host := range hosts {
+		if _, known := c.hostPos[host]; !known {
+			c.hostPos[host] = pos
+		}
+	}
+}
+
+// appendUnique appends the values not already present in list
+func appendUnique(list []string, values ...string) []string {
+	seen := make(map[string]bool, len(list))
+	for _, v := range list {
+		seen[v] = true
+	}
+	for _, v := range values {
+		if !seen[v] {
+			list = append(list, v)
+			seen[v] = true
+		}
+	}
+	return list
+}
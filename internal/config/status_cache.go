@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CachedStatus is the result of the status probes of a host, with the time
+// it was last probed successfully
+type CachedStatus struct {
+	Time    time.Time               `json:"time"`
+	Metrics map[string]CachedMetric `json:"metrics"`
+}
+
+// CachedMetric is a probed value, or the reason its probe failed, and when it
+// was probed
+type CachedMetric struct {
+	Value string    `json:"value,omitempty"`
+	Err   string    `json:"err,omitempty"`
+	Time  time.Time `json:"time,omitempty"`
+}
+
+// Measured returns when the oldest of the metrics in names was probed, and
+// whether every one of them is cached
+func (s CachedStatus) Measured(names []string) (time.Time, bool) {
+	oldest := s.Time
+	for _, name := range names {
+		metric, ok := s.Metrics[name]
+		if !ok {
+			return time.Time{}, false
+		}
+		// Entries written before metrics had their own time
+		if !metric.Time.IsZero() && metric.Time.Before(oldest) {
+			oldest = metric.Time
+		}
+	}
+	return oldest, true
+}
+
+// GetStatusCachePath returns the full path to the status cache
+func GetStatusCachePath() string {
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "status.json")
+}
+
+// LoadStatusCache loads the cached status probe results by host. A missing
+// cache file yields an empty map.
+func LoadStatusCache() (map[string]CachedStatus, error) {
+	cache := make(map[string]CachedStatus)
+
+	cachePath := GetStatusCachePath()
+	if cachePath == "" {
+		return cache, nil
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status cache %s: %v", cachePath, err)
+	}
+
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse status cache %s: %v", cachePath, err)
+	}
+	return cache, nil
+}
+
+// UpdateStatusCache merges the metrics of the hosts in updates into the cache,
+// keeping metrics that were not probed again and the results of other hosts.
+// The cache is replaced atomically so that concurrent readers never see a
+// partially written file.
+func UpdateStatusCache(updates map[string]CachedStatus) error {
+	cachePath := GetStatusCachePath()
+	if cachePath == "" {
+		return fmt.Errorf("cannot determine cache directory: neither XDG_CACHE_HOME nor HOME is set")
+	}
+
+	// Reload so results written by other runs since are kept
+	cache, err := LoadStatusCache()
+	if err != nil {
+		cache = make(map[string]CachedStatus)
+	}
+	for host, status := range updates {
+		entry := cache[host]
+		if entry.Metrics == nil {
+			entry.Metrics = make(map[string]CachedMetric)
+		}
+		for name, metric := range status.Metrics {
+			entry.Metrics[name] = metric
+		}
+		entry.Time = status.Time
+		cache[host] = entry
+	}
+
+	dir := filepath.Dir(cachePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode status cache: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".status-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write status cache %s: %v", cachePath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write status cache %s: %v", cachePath, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %v", cachePath, err)
+	}
+
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		return fmt.Errorf("failed to replace status cache %s: %v", cachePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Threshold is a warning and a critical level for a numeric status metric.
+// Higher values are worse, unless Warn is greater than Crit.
+type Threshold struct {
+	Metric string
+	Warn   float64
+	Crit   float64
+	// Unit is "%" when the levels were written as percentages, used when
+	// displaying them
+	Unit string
+
+	// scope limits the threshold to a host or group when set
+	scope *hostRule
+}
+
+// parseThreshold parses the arguments of a threshold directive: a metric, an
+// optional host or @group, and the warning and critical levels
+func (c *HostConfig) parseThreshold(fields []string, pos position) (Threshold, error) {
+	threshold := Threshold{Metric: fields[0]}
+	if !columnName.MatchString(threshold.Metric) {
+		return threshold, fmt.Errorf("invalid metric name '%s'", threshold.Metric)
+	}
+
+	levels := fields[1:]
+	switch len(levels) {
+	case 2:
+	case 3:
+		rule := c.addRule(levels[0], pos)
+		threshold.scope = &rule
+		levels = levels[1:]
+	default:
+		return threshold, fmt.Errorf("threshold for '%s' requires a warning and a critical level", threshold.Metric)
+	}
+
+	var err error
+	if threshold.Warn, err = threshold.parseLevel(levels[0]); err != nil {
+		return threshold, err
+	}
+	if threshold.Crit, err = threshold.parseLevel(levels[1]); err != nil {
+		return threshold, err
+	}
+	return threshold, nil
+}
+
+// parseLevel parses a level such as 80 or 80%
+func (t *Threshold) parseLevel(level string) (float64, error) {
+	number := strings.TrimSuffix(level, "%")
+	if number != level {
+		t.Unit = "%"
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid level '%s' for '%s'", level, t.Metric)
+	}
+	return value, nil
+}
+
+// Threshold returns the threshold of metric on host and whether one is set.
+// Host thresholds override group thresholds, which override global ones,
+// and later directives override earlier ones of the same kind.
+func (c *HostConfig) Threshold(host, metric string) (Threshold, bool) {
+	var threshold Threshold
+	found := false
+	for _, kind := range []func(*hostRule) bool{
+		func(scope *hostRule) bool { return scope == nil },
+		func(scope *hostRule) bool { return scope != nil && scope.isGroup() },
+		func(scope *hostRule) bool { return scope != nil && !scope.isGroup() },
+	} {
+		for _, t := range c.thresholds {
+			if t.Metric != metric || !kind(t.scope) {
+				continue
+			}
+			if t.scope != nil && !t.scope.appliesTo(c, host) {
+				continue
+			}
+			threshold, found = t, true
+		}
+	}
+	return threshold, found
+}
+
+// ThresholdMetrics returns the metrics that have a threshold for any host, in
+// the order they were first given one
+func (c *HostConfig) ThresholdMetrics() []string {
+	var metrics []string
+	for _, t := range c.thresholds {
+		metrics = appendUnique(metrics, t.Metric)
+	}
+	return metrics
+}
+
+// Critical reports whether value reaches the critical level
+func (t Threshold) Critical(value float64) bool {
+	return t.reaches(value, t.Crit)
+}
+
+// Warning reports whether value reaches the warning level
+func (t Threshold) Warning(value float64) bool {
+	return t.reaches(value, t.Warn)
+}
+
+func (t Threshold) reaches(value, level float64) bool {
+	if t.Warn > t.Crit {
+		return value <= level
+	}
+	return value >= level
+}
+
+// FormatLevel formats a level of the threshold with its unit
+func (t Threshold) FormatLevel(level float64) string {
+	return strconv.FormatFloat(level, 'f', -1, 64) + t.Unit
+}
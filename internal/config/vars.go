@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hostRule is the target of a directive that applies to a host or to every
+// host in a @group
+type hostRule struct {
+	target string
+	pos    position
+}
+
+// isGroup reports whether the rule targets a group
+func (r hostRule) isGroup() bool {
+	return strings.HasPrefix(r.target, "@")
+}
+
+// appliesTo reports whether the rule targets host, directly or through group
+// membership
+func (r hostRule) appliesTo(c *HostConfig, host string) bool {
+	if !r.isGroup() {
+		return r.target == host
+	}
+	hosts, err := c.ExpandSelector(r.target)
+	if err != nil {
+		return false
+	}
+	return containsString(hosts, host)
+}
+
+// varRule assigns variables to a host or group
+type varRule struct {
+	hostRule
+	values map[string]string
+}
+
+// HostData is the information about a host that is available to command
+// templates
+type HostData struct {
+	Host   string
+	Class  string
+	Groups []string
+	Vars   map[string]string
+}
+
+// parseVars parses the key=value arguments of a var directive
+func parseVars(fields []string) (map[string]string, error) {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("expected key=value, got '%s'", field)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// addRule records the target of a host or group directive
+func (c *HostConfig) addRule(target string, pos position) hostRule {
+	rule := hostRule{target: target, pos: pos}
+	c.rules = append(c.rules, rule)
+	return rule
+}
+
+// Vars returns the variables of host. Variables from inventory sources are
+// overridden by group variables, which are overridden by host variables; rules
+// of the same kind apply in the order they appear in the config.
+func (c *HostConfig) Vars(host string) map[string]string {
+	vars := make(map[string]string)
+	for key, value := range c.sourceVars[host] {
+		vars[key] = value
+	}
+
+	for _, groupRules := range []bool{true, false} {
+		for _, rule := range c.vars {
+			if rule.isGroup() != groupRules || !rule.appliesTo(c, host) {
+				continue
+			}
+			for key, value := range rule.values {
+				vars[key] = value
+			}
+		}
+	}
+	return vars
+}
+
+// HostData returns the template data for host
+func (c *HostConfig) HostData(host string) HostData {
+	return HostData{
+		Host:   host,
+		Class:  c.Classes[host],
+		Groups: c.GroupsOf(host),
+		Vars:   c.Vars(host),
+	}
+}
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -21,26 +22,74 @@ type Result struct {
 	Stdout   string
 	Stderr   string
 	Err      error
+	// Duration is how long the command ran
+	Duration time.Duration
 }
 
-func verifyHostsAndCommand(hosts []string, command string) error {
+// Limits bound parallel execution. Zero leaves either limit unset.
+type Limits struct {
+	// Concurrency is the number of commands run at a time
+	Concurrency int
+	// Timeout is how long a command may run before it is killed
+	Timeout time.Duration
+}
+
+// slots returns a semaphore limiting parallel execution, or nil when it is
+// unlimited
+func (l Limits) slots() chan struct{} {
+	if l.Concurrency <= 0 {
+		return nil
+	}
+	return make(chan struct{}, l.Concurrency)
+}
+
+// executeLimited runs execute within limits, holding a slot of sem if it is set
+func executeLimited(sem chan struct{}, limits Limits, hostname, command string, isLocal bool) Result {
+	if sem != nil {
+		sem <- struct{}{}
+		defer func() { <-sem }()
+	}
+	return execute(hostname, command, isLocal, limits.Timeout)
+}
+
+func verifyHostsAndCommands(hosts []string, commands []string) error {
 	if len(hosts) == 0 {
 		return errors.New("at least one hostname must be specified")
 	}
-	if strings.TrimSpace(command) == "" {
-		return errors.New("command cannot be empty")
+	if len(commands) != len(hosts) {
+		return errors.New("exactly one command per host must be specified")
+	}
+	for _, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			return errors.New("command cannot be empty")
+		}
 	}
 	return nil
 }
 
+// repeatCommand returns a slice holding command n times
+func repeatCommand(command string, n int) []string {
+	commands := make([]string, n)
+	for i := range commands {
+		commands[i] = command
+	}
+	return commands
+}
+
 func ExecuteOnHostsInteractive(hosts []string, command string) error {
-	if err := verifyHostsAndCommand(hosts, command); err != nil {
+	return ExecuteCommandsInteractive(hosts, repeatCommand(command, len(hosts)))
+}
+
+// ExecuteCommandsInteractive executes commands[i] on hosts[i] sequentially
+// with direct stdin/stdout/stderr
+func ExecuteCommandsInteractive(hosts []string, commands []string) error {
+	if err := verifyHostsAndCommands(hosts, commands); err != nil {
 		return nil
 	}
 
-	for _, hostname := range hosts {
+	for i, hostname := range hosts {
 		isLocal := hostname == "localhost"
-		if err := executeInteractive(hostname, command, isLocal); err != nil {
+		if err := executeInteractive(hostname, commands[i], isLocal); err != nil {
 			return err
 		}
 	}
@@ -48,19 +97,31 @@ func ExecuteOnHostsInteractive(hosts []string, command string) error {
 }
 
 func ExecuteOnHostsParallel(hosts []string, command string) ([]Result, error) {
-	if err := verifyHostsAndCommand(hosts, command); err != nil {
+	return ExecuteCommandsParallel(hosts, repeatCommand(command, len(hosts)))
+}
+
+// ExecuteCommandsParallel executes commands[i] on hosts[i] in parallel
+func ExecuteCommandsParallel(hosts []string, commands []string) ([]Result, error) {
+	return ExecuteCommandsParallelLimited(hosts, commands, Limits{})
+}
+
+// ExecuteCommandsParallelLimited executes commands[i] on hosts[i] in parallel
+// within limits
+func ExecuteCommandsParallelLimited(hosts []string, commands []string, limits Limits) ([]Result, error) {
+	if err := verifyHostsAndCommands(hosts, commands); err != nil {
 		return nil, nil
 	}
 
 	results := make([]Result, len(hosts))
 	var wg sync.WaitGroup
+	sem := limits.slots()
 
 	for i, hostname := range hosts {
 		wg.Add(1)
 		go func(i int, host string) {
 			defer wg.Done()
 			isLocal := host == "localhost"
-			results[i] = execute(host, command, isLocal)
+			results[i] = executeLimited(sem, limits, host, commands[i], isLocal)
 		}(i, hostname)
 	}
 	wg.Wait()
@@ -70,13 +131,19 @@ func ExecuteOnHostsParallel(hosts []string, command string) ([]Result, error) {
 
 // ExecuteOnHostsParallelWithProgress executes commands on hosts with optional progress indicator
 func ExecuteOnHostsParallelWithProgress(hosts []string, command string, progressMessage string) ([]Result, error) {
-	if err := verifyHostsAndCommand(hosts, command); err != nil {
+	return ExecuteCommandsParallelWithProgress(hosts, repeatCommand(command, len(hosts)), progressMessage)
+}
+
+// ExecuteCommandsParallelWithProgress executes commands[i] on hosts[i] in
+// parallel with optional progress indicator
+func ExecuteCommandsParallelWithProgress(hosts []string, commands []string, progressMessage string) ([]Result, error) {
+	if err := verifyHostsAndCommands(hosts, commands); err != nil {
 		return nil, nil
 	}
 
 	// Skip progress indicator for single host or when disabled
 	if len(hosts) == 1 {
-		return ExecuteOnHostsParallel(hosts, command)
+		return ExecuteCommandsParallel(hosts, commands)
 	}
 
 	if progressMessage == "" {
@@ -98,7 +165,7 @@ func ExecuteOnHostsParallelWithProgress(hosts []string, command string, progress
 		go func(i int, host string) {
 			defer wg.Done()
 			isLocal := host == "localhost"
-			results[i] = execute(host, command, isLocal)
+			results[i] = execute(host, commands[i], isLocal, 0)
 
 			// Update progress
 			mu.Lock()
@@ -144,18 +211,35 @@ func ResultsError(results []Result) error {
 	return nil
 }
 
-func execute(hostname, command string, isLocal bool) Result {
+// execute runs command on hostname, killing it after timeout unless timeout
+// is zero
+func execute(hostname, command string, isLocal bool, timeout time.Duration) Result {
 	result := Result{Hostname: hostname, Command: command}
 
-	cmd := exec.Command("ssh", hostname, command)
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "ssh", SSHArgs(hostname, nil, command)...)
 	if isLocal {
-		cmd = exec.Command("bash", "-c", command)
+		cmd = exec.CommandContext(ctx, "bash", "-c", command)
 	}
+	// Do not wait for the output of processes left behind by a killed command
+	cmd.WaitDelay = time.Second
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Err = fmt.Errorf("error executing on %s: timed out after %s", hostname, timeout)
+		stderr.WriteString("\ntimed out after " + timeout.String())
+	} else if err != nil {
 		result.Err = fmt.Errorf("error executing on %s: %v", hostname, err)
 	}
 
@@ -167,7 +251,7 @@ func execute(hostname, command string, isLocal bool) Result {
 func executeInteractive(hostname, command string, isLocal bool) error {
 	fmt.Printf("%s Executing on %s: %s\n", colors.Header.Sprint("==="), colors.Hostname.Sprint(hostname), command)
 
-	cmd := exec.Command("ssh", "-t", hostname, command)
+	cmd := exec.Command("ssh", SSHArgs(hostname, []string{"-t"}, command)...)
 	if isLocal {
 		cmd = exec.Command("bash", "-c", command)
 	}
@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// sshOptions returns the extra ssh options for a host. It is set from the
+// host configuration with SetSSHOptions.
+var sshOptions = func(host string) []string { return nil }
+
+// SetSSHOptions sets the function returning the ssh options used to connect
+// to each host. The options are applied to every ssh and scp invocation.
+func SetSSHOptions(options func(host string) []string) {
+	sshOptions = options
+}
+
+// sharedConnections holds the ssh options that keep connections open between
+// commands, set with ShareConnections
+var sharedConnections []string
+
+// ShareConnections makes ssh keep a master connection per host open in dir
+// for persist after its last use, so later commands to the host skip the
+// handshake. Options set for a host take precedence.
+func ShareConnections(dir string, persist time.Duration) {
+	sharedConnections = []string{
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + filepath.Join(dir, "%C"),
+		"-o", fmt.Sprintf("ControlPersist=%d", int(persist.Seconds())),
+	}
+}
+
+// connectionOptions returns the ssh options used to connect to host
+func connectionOptions(host string) []string {
+	options := append([]string{}, sshOptions(host)...)
+	return append(options, sharedConnections...)
+}
+
+// SSHArgs returns the ssh arguments that run command on host, with flags
+// placed before the connection options
+func SSHArgs(host string, flags []string, command ...string) []string {
+	args := append([]string{}, flags...)
+	args = append(args, connectionOptions(host)...)
+	args = append(args, host)
+	return append(args, command...)
+}
+
+// SSHCommand returns a command running command on host over ssh
+func SSHCommand(host string, command ...string) *exec.Cmd {
+	return exec.Command("ssh", SSHArgs(host, nil, command...)...)
+}
+
+// SCPCommand returns a command copying localPath to remotePath on host
+func SCPCommand(host, localPath, remotePath string) *exec.Cmd {
+	args := append(connectionOptions(host), localPath, host+":"+remotePath)
+	return exec.Command("scp", args...)
+}